@@ -2,13 +2,39 @@ package cliutil
 
 import (
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-cli/dockerapi"
 	"github.com/ngageoint/seed-common/util"
 )
 
+var dockerClient *dockerapi.Client
+var dockerClientOnce sync.Once
+
+//DockerClient returns a client for the Docker Engine API, or nil if the engine socket
+// can't be reached, SEED_DOCKER_EXEC is set or another runtime is selected. Callers fall
+// back to running the runtime command when no client is available.
+func DockerClient() *dockerapi.Client {
+	dockerClientOnce.Do(func() {
+		if os.Getenv(constants.DockerExecEnv) != "" || CurrentRuntime().Name() != constants.DockerRuntime {
+			return
+		}
+		client, err := dockerapi.NewClient()
+		if err != nil {
+			util.PrintUtil("INFO: Unable to connect to the Docker Engine API; falling back to the docker command. %s\n", err.Error())
+			return
+		}
+		dockerClient = client
+	})
+	return dockerClient
+}
+
 //DockerCommandArgsInit returns the initial command and args needed to run docker based on the OS seed CLI is running in.
 func DockerCommandArgsInit() ([]string, string) {
 	var dockerArgs []string
@@ -47,3 +73,27 @@ func CheckDockerAccess() bool {
 	}
 	return returnValue
 }
+
+//StopContainer stops the named container, giving it gracePeriod seconds to exit before
+// the runtime kills it. If stop fails, the container is killed outright.
+func StopContainer(name string, gracePeriod int) error {
+	dockerArgs, dockerCommand := CommandArgsInit()
+
+	stopArgs := append([]string{}, dockerArgs...)
+	stopArgs = append(stopArgs, "stop", "-t", strconv.Itoa(gracePeriod), name)
+	if err := exec.Command(dockerCommand, stopArgs...).Run(); err == nil {
+		return nil
+	}
+
+	killArgs := append([]string{}, dockerArgs...)
+	killArgs = append(killArgs, "kill", name)
+	return exec.Command(dockerCommand, killArgs...).Run()
+}
+
+//RemoveContainer force removes the named container
+func RemoveContainer(name string) error {
+	dockerArgs, dockerCommand := CommandArgsInit()
+	rmArgs := append([]string{}, dockerArgs...)
+	rmArgs = append(rmArgs, "rm", "-f", name)
+	return exec.Command(dockerCommand, rmArgs...).Run()
+}
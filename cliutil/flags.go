@@ -0,0 +1,88 @@
+package cliutil
+
+import (
+	"flag"
+	"strings"
+)
+
+//KeyValueFlags is a flag taking KEY=VALUE arguments that may be repeated, i.e.
+// -i A=x -i B=y. For compatibility a single argument may also list several pairs
+// separated by commas, i.e. -i A=x,B=y. A comma only separates pairs when it is followed
+// by another KEY=, so commas within values such as JSON or paths are kept. A comma
+// that would otherwise start a new pair is kept in the value when escaped as \,
+type KeyValueFlags []string
+
+//String returns the values separated by commas, with commas in values escaped
+func (f *KeyValueFlags) String() string {
+	var escaped []string
+	for _, v := range *f {
+		escaped = append(escaped, strings.Replace(v, ",", `\,`, -1))
+	}
+	return strings.Join(escaped, ",")
+}
+
+//Set adds the KEY=VALUE pairs in one argument of the flag
+func (f *KeyValueFlags) Set(value string) error {
+	*f = append(*f, SplitKeyValues(value)...)
+	return nil
+}
+
+//Get returns the values given with the flag as a []string
+func (f *KeyValueFlags) Get() interface{} {
+	return []string(*f)
+}
+
+//SplitKeyValues splits a comma separated list of KEY=VALUE pairs. A comma separates
+// pairs only when followed by a key and =; an escaped comma (\,) never does and is
+// replaced by a plain comma.
+func SplitKeyValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	var values []string
+	var current []byte
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == ',':
+			current = append(current, ',')
+			i++
+		case value[i] == ',' && startsWithKey(value[i+1:]):
+			values = append(values, string(current))
+			current = nil
+		default:
+			current = append(current, value[i])
+		}
+	}
+	return append(values, string(current))
+}
+
+//startsWithKey returns whether s starts with a key followed by =. Keys are the names of
+// inputs, settings, mounts and resources: letters, digits, _ and -, not starting with a digit.
+func startsWithKey(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '=':
+			return i > 0
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case (c >= '0' && c <= '9') || c == '-':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+//FlagValues returns the values given with a KeyValueFlags flag of cmd
+func FlagValues(cmd *flag.FlagSet, name string) []string {
+	if getter, ok := cmd.Lookup(name).Value.(flag.Getter); ok {
+		if values, ok := getter.Get().([]string); ok {
+			return values
+		}
+	}
+	return nil
+}
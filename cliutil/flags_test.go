@@ -0,0 +1,73 @@
+package cliutil
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestSplitKeyValues(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected []string
+	}{
+		{"", nil},
+		{"A=x", []string{"A=x"}},
+		{"A=x,B=y", []string{"A=x", "B=y"}},
+		{"INPUT_FILE=/data/a,b.tif", []string{"INPUT_FILE=/data/a,b.tif"}},
+		{"MULTIPLE=a.tif,b.tif,OTHER=c.tif", []string{"MULTIPLE=a.tif,b.tif", "OTHER=c.tif"}},
+		{`PARAMS={"a":1,"b":[2,3]}`, []string{`PARAMS={"a":1,"b":[2,3]}`}},
+		{`PARAMS={"a":1},MODE=fast`, []string{`PARAMS={"a":1}`, "MODE=fast"}},
+		{"LIST=x,y=z", []string{"LIST=x", "y=z"}},
+		{`LIST=x\,y=z`, []string{"LIST=x,y=z"}},
+		{`MSG=a\,b,c`, []string{"MSG=a,b,c"}},
+		{`WIN=C:\data\in.tif,OUT=1`, []string{`WIN=C:\data\in.tif`, "OUT=1"}},
+		{"EXPR=a==b,c", []string{"EXPR=a==b,c"}},
+		{"A=,B=", []string{"A=", "B="}},
+		{"A=x,,B=y", []string{"A=x,", "B=y"}},
+		{"A=x,1B=y", []string{"A=x,1B=y"}},
+		{"cpus=2,mem=max:1024,all=x0.5", []string{"cpus=2", "mem=max:1024", "all=x0.5"}},
+		{"my-mount=/a,other-mount=/b", []string{"my-mount=/a", "other-mount=/b"}},
+		{"A=x,=y", []string{"A=x,=y"}},
+	}
+
+	for _, c := range cases {
+		if result := SplitKeyValues(c.value); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("SplitKeyValues(%q) == %q, expected %q", c.value, result, c.expected)
+		}
+	}
+}
+
+func TestKeyValueFlags(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{}, nil},
+		{[]string{"-e", "A=x", "-e", "B=y"}, []string{"A=x", "B=y"}},
+		{[]string{"-e", "A=x,B=y", "-setting", "C=z"}, []string{"A=x", "B=y", "C=z"}},
+		{[]string{"-e", "A=1,2,3", "-e", `B={"k":[1,2]}`}, []string{"A=1,2,3", `B={"k":[1,2]}`}},
+		{[]string{"-e", `A=x\,B=y`}, []string{"A=x,B=y"}},
+	}
+
+	for _, c := range cases {
+		cmd := flag.NewFlagSet("test", flag.ContinueOnError)
+		var settings KeyValueFlags
+		cmd.Var(&settings, "setting", "")
+		cmd.Var(&settings, "e", "")
+		if err := cmd.Parse(c.args); err != nil {
+			t.Errorf("Parse(%q) returned an error: %v", c.args, err)
+			continue
+		}
+		if result := FlagValues(cmd, "setting"); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("FlagValues after Parse(%q) == %q, expected %q", c.args, result, c.expected)
+		}
+
+		// the string form parses back to the same values
+		var again KeyValueFlags
+		again.Set(settings.String())
+		if len(c.expected) > 0 && !reflect.DeepEqual([]string(again), c.expected) {
+			t.Errorf("Set(%q) == %q, expected %q", settings.String(), again, c.expected)
+		}
+	}
+}
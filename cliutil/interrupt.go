@@ -0,0 +1,72 @@
+package cliutil
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var interruptMutex sync.Mutex
+var interruptHandlers []*func()
+var interrupted bool
+
+//OnInterrupt registers f to run if seed is interrupted by SIGINT or SIGTERM. Handlers
+// run in the reverse order they were registered. Returns a function that unregisters f
+// once whatever it cleans up is gone.
+func OnInterrupt(f func()) func() {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	handler := &f
+	interruptHandlers = append(interruptHandlers, handler)
+
+	return func() {
+		interruptMutex.Lock()
+		defer interruptMutex.Unlock()
+		for i, h := range interruptHandlers {
+			if h == handler {
+				interruptHandlers = append(interruptHandlers[:i], interruptHandlers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+//Interrupted returns whether seed has been interrupted, so no new work should be started
+func Interrupted() bool {
+	interruptMutex.Lock()
+	defer interruptMutex.Unlock()
+	return interrupted
+}
+
+//HandleInterrupts runs the registered interrupt handlers when seed receives SIGINT or
+// SIGTERM, then exits with 128 plus the signal number as a shell would. A second signal
+// exits immediately without waiting for the handlers to finish.
+func HandleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		code := 128 + int(sig.(syscall.Signal))
+		go func() {
+			<-signals
+			os.Exit(code)
+		}()
+		runInterruptHandlers()
+		os.Exit(code)
+	}()
+}
+
+//runInterruptHandlers marks seed as interrupted and runs the registered handlers, most
+// recently registered first
+func runInterruptHandlers() {
+	interruptMutex.Lock()
+	interrupted = true
+	handlers := append([]*func(){}, interruptHandlers...)
+	interruptHandlers = nil
+	interruptMutex.Unlock()
+
+	for i := len(handlers) - 1; i >= 0; i-- {
+		(*handlers[i])()
+	}
+}
@@ -0,0 +1,32 @@
+package cliutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRunInterruptHandlers(t *testing.T) {
+	defer func() { interrupted = false }()
+
+	var ran []string
+	OnInterrupt(func() { ran = append(ran, "batch journal") })
+	removeTemp := OnInterrupt(func() { ran = append(ran, "temp dir") })
+	OnInterrupt(func() { ran = append(ran, "container") })
+	removeTemp()
+
+	if Interrupted() {
+		t.Errorf("Interrupted() returned true before any signal")
+	}
+	runInterruptHandlers()
+
+	expected := []string{"container", "batch journal"}
+	if !reflect.DeepEqual(ran, expected) {
+		t.Errorf("runInterruptHandlers ran %v, expected %v", ran, expected)
+	}
+	if !Interrupted() {
+		t.Errorf("Interrupted() returned false after handlers ran")
+	}
+	if len(interruptHandlers) != 0 {
+		t.Errorf("runInterruptHandlers left %v handlers registered", len(interruptHandlers))
+	}
+}
@@ -0,0 +1,205 @@
+package cliutil
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//Runtime is a container runtime CLI seed can build and run images with
+type Runtime interface {
+	// Name of the runtime, as given to the -runtime flag
+	Name() string
+
+	// CommandArgsInit returns the command and initial args needed to run the runtime
+	CommandArgsInit() ([]string, string)
+
+	// HasLabel returns whether the runtime supports image labels
+	HasLabel() bool
+
+	// HasReferenceFilter returns whether the runtime supports images --filter=reference
+	HasReferenceFilter() bool
+
+	// GPUArgs returns the run args giving a container access to the given number of GPUs
+	GPUArgs(gpus int) []string
+
+	// AuthEnv returns the environment variable and path used to point the runtime at a
+	// separate registry login under configDir
+	AuthEnv(configDir string) (string, string)
+}
+
+var runtimes = map[string]Runtime{
+	constants.DockerRuntime: dockerRuntime{},
+	constants.PodmanRuntime: podmanRuntime{},
+}
+
+var currentRuntime Runtime
+var currentRuntimeOnce sync.Once
+
+//runtimeVersions caches the client version of each runtime command, which doesn't
+// change while seed runs
+var runtimeVersions = map[string]string{}
+var runtimeVersionsMutex sync.Mutex
+
+//SetRuntime selects the container runtime by name
+func SetRuntime(name string) error {
+	r, ok := runtimes[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("ERROR: Unknown container runtime %s. Supported runtimes are %s and %s",
+			name, constants.DockerRuntime, constants.PodmanRuntime)
+	}
+	currentRuntime = r
+	return nil
+}
+
+//CurrentRuntime returns the selected container runtime. If none was selected with
+// SetRuntime, the runtime named by the SEED_RUNTIME environment variable is used,
+// defaulting to docker. An unknown runtime in SEED_RUNTIME is reported and docker used.
+func CurrentRuntime() Runtime {
+	currentRuntimeOnce.Do(func() {
+		if currentRuntime != nil {
+			return
+		}
+		currentRuntime = dockerRuntime{}
+		if name := os.Getenv(constants.RuntimeEnv); name != "" {
+			if err := SetRuntime(name); err != nil {
+				util.PrintUtil("%s. Using %s.\n", err.Error(), constants.DockerRuntime)
+			}
+		}
+	})
+	return currentRuntime
+}
+
+//CommandArgsInit returns the command and initial args needed to run the selected container runtime
+func CommandArgsInit() ([]string, string) {
+	return CurrentRuntime().CommandArgsInit()
+}
+
+type dockerRuntime struct{}
+
+func (dockerRuntime) Name() string {
+	return constants.DockerRuntime
+}
+
+func (dockerRuntime) CommandArgsInit() ([]string, string) {
+	return DockerCommandArgsInit()
+}
+
+// labels were added in docker 1.6
+func (dockerRuntime) HasLabel() bool {
+	return versionAtLeast(runtimeVersion(constants.DockerRuntime), "1.6")
+}
+
+// the reference filter was added in docker 1.13
+func (dockerRuntime) HasReferenceFilter() bool {
+	return versionAtLeast(runtimeVersion(constants.DockerRuntime), "1.13")
+}
+
+// docker exposes GPUs through the nvidia container runtime
+func (dockerRuntime) GPUArgs(gpus int) []string {
+	return []string{"--runtime=nvidia", "-e", "NVIDIA_VISIBLE_DEVICES=" + gpuList(gpus)}
+}
+
+func (dockerRuntime) AuthEnv(configDir string) (string, string) {
+	return "DOCKER_CONFIG", configDir
+}
+
+//podmanRuntime runs rootless podman, which never needs sudo
+type podmanRuntime struct{}
+
+func (podmanRuntime) Name() string {
+	return constants.PodmanRuntime
+}
+
+func (podmanRuntime) CommandArgsInit() ([]string, string) {
+	return nil, constants.PodmanRuntime
+}
+
+func (podmanRuntime) HasLabel() bool {
+	return true
+}
+
+// podman images has supported the reference filter since 1.0
+func (podmanRuntime) HasReferenceFilter() bool {
+	return versionAtLeast(runtimeVersion(constants.PodmanRuntime), "1.0")
+}
+
+// podman exposes GPUs as CDI devices
+func (podmanRuntime) GPUArgs(gpus int) []string {
+	var args []string
+	for g := 0; g < gpus; g++ {
+		args = append(args, "--device", "nvidia.com/gpu="+strconv.Itoa(g))
+	}
+	return append(args, "-e", "NVIDIA_VISIBLE_DEVICES="+gpuList(gpus))
+}
+
+func (podmanRuntime) AuthEnv(configDir string) (string, string) {
+	return "REGISTRY_AUTH_FILE", configDir + string(os.PathSeparator) + "auth.json"
+}
+
+//gpuList returns the comma separated IDs of the first gpus GPUs, i.e. 0,1,2
+func gpuList(gpus int) string {
+	var ids []string
+	for g := 0; g < gpus; g++ {
+		ids = append(ids, strconv.Itoa(g))
+	}
+	return strings.Join(ids, ",")
+}
+
+//runtimeVersion returns the client version reported by the runtime command, or an
+// empty string if it can't be determined. The command is only run once.
+func runtimeVersion(command string) string {
+	runtimeVersionsMutex.Lock()
+	defer runtimeVersionsMutex.Unlock()
+	if version, ok := runtimeVersions[command]; ok {
+		return version
+	}
+
+	// docker exits with an error if the daemon can't be reached but still prints the client version
+	out, _ := exec.Command(command, "version", "--format", "{{.Client.Version}}").Output()
+	version := strings.TrimSpace(string(out))
+	runtimeVersions[command] = version
+	return version
+}
+
+//versionAtLeast returns whether the dotted version is at least min. Any suffix such as
+// -ce is ignored.
+func versionAtLeast(version, min string) bool {
+	if version == "" {
+		return false
+	}
+	v := versionNumbers(version)
+	m := versionNumbers(min)
+	for i := range m {
+		n := 0
+		if i < len(v) {
+			n = v[i]
+		}
+		if n != m[i] {
+			return n > m[i]
+		}
+	}
+	return true
+}
+
+func versionNumbers(version string) []int {
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		n, _ := strconv.Atoi(part[:end])
+		numbers = append(numbers, n)
+		if end < len(part) {
+			break
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,205 @@
+package cliutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+//fakeRuntimeScript stands in for the docker and podman binaries. It prints the version
+// in FAKE_RUNTIME_VERSION and appends its arguments to FAKE_RUNTIME_LOG.
+const fakeRuntimeScript = `#!/bin/sh
+echo "$@" >> "$FAKE_RUNTIME_LOG"
+if [ "$1" = "version" ]; then
+	echo "$FAKE_RUNTIME_VERSION"
+fi
+exit 0
+`
+
+//resetRuntime forgets the selected runtime and the cached runtime versions
+func resetRuntime() {
+	currentRuntime = nil
+	currentRuntimeOnce = sync.Once{}
+	runtimeVersions = map[string]string{}
+}
+
+//fakeRuntime puts fake docker and podman binaries first on the PATH and returns the
+// file their arguments are logged to
+func fakeRuntime(t *testing.T, version string) (string, func()) {
+	resetRuntime()
+	dir, err := ioutil.TempDir("", "seed-runtime-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{constants.DockerRuntime, constants.PodmanRuntime} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(fakeRuntimeScript), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log := filepath.Join(dir, "args.log")
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	os.Setenv("FAKE_RUNTIME_LOG", log)
+	os.Setenv("FAKE_RUNTIME_VERSION", version)
+
+	return log, func() {
+		os.Setenv("PATH", path)
+		os.Unsetenv("FAKE_RUNTIME_LOG")
+		os.Unsetenv("FAKE_RUNTIME_VERSION")
+		os.RemoveAll(dir)
+		resetRuntime()
+	}
+}
+
+func TestSetRuntime(t *testing.T) {
+	defer resetRuntime()
+
+	cases := []struct {
+		name     string
+		expected string
+		errStr   string
+	}{
+		{"docker", constants.DockerRuntime, ""},
+		{"podman", constants.PodmanRuntime, ""},
+		{"Podman", constants.PodmanRuntime, ""},
+		{"rkt", "", "Unknown container runtime rkt"},
+	}
+
+	for _, c := range cases {
+		resetRuntime()
+		err := SetRuntime(c.name)
+		if c.errStr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errStr) {
+				t.Errorf("SetRuntime(%v) returned error %v, expected %v", c.name, err, c.errStr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SetRuntime(%v) returned an error: %v", c.name, err)
+		} else if CurrentRuntime().Name() != c.expected {
+			t.Errorf("SetRuntime(%v) selected %v, expected %v", c.name, CurrentRuntime().Name(), c.expected)
+		}
+	}
+}
+
+func TestCurrentRuntimeEnv(t *testing.T) {
+	defer resetRuntime()
+	defer os.Unsetenv(constants.RuntimeEnv)
+
+	cases := []struct {
+		env      string
+		expected string
+	}{
+		{"", constants.DockerRuntime},
+		{"podman", constants.PodmanRuntime},
+		{"docker", constants.DockerRuntime},
+		{"rkt", constants.DockerRuntime},
+	}
+
+	for _, c := range cases {
+		resetRuntime()
+		os.Setenv(constants.RuntimeEnv, c.env)
+		if name := CurrentRuntime().Name(); name != c.expected {
+			t.Errorf("CurrentRuntime() with %v=%v returned %v, expected %v", constants.RuntimeEnv, c.env, name, c.expected)
+		}
+	}
+}
+
+func TestRuntimeFeatures(t *testing.T) {
+	cases := []struct {
+		runtime   string
+		version   string
+		label     bool
+		reference bool
+	}{
+		{constants.DockerRuntime, "1.5.0", false, false},
+		{constants.DockerRuntime, "1.12.6", true, false},
+		{constants.DockerRuntime, "17.03.0-ce", true, true},
+		{constants.DockerRuntime, "", false, false},
+		{constants.PodmanRuntime, "0.12.1", true, false},
+		{constants.PodmanRuntime, "4.3.1", true, true},
+	}
+
+	for _, c := range cases {
+		log, done := fakeRuntime(t, c.version)
+		r := runtimes[c.runtime]
+		if label := r.HasLabel(); label != c.label {
+			t.Errorf("%v %v HasLabel() returned %v, expected %v", c.runtime, c.version, label, c.label)
+		}
+		if reference := r.HasReferenceFilter(); reference != c.reference {
+			t.Errorf("%v %v HasReferenceFilter() returned %v, expected %v", c.runtime, c.version, reference, c.reference)
+		}
+		r.HasReferenceFilter()
+		if data, _ := ioutil.ReadFile(log); strings.Count(string(data), "version") != 1 {
+			t.Errorf("%v %v ran the version command %d times, expected once", c.runtime, c.version, strings.Count(string(data), "version"))
+		}
+		done()
+	}
+}
+
+func TestGPUArgs(t *testing.T) {
+	cases := []struct {
+		runtime  string
+		gpus     int
+		expected []string
+	}{
+		{constants.DockerRuntime, 2, []string{"--runtime=nvidia", "-e", "NVIDIA_VISIBLE_DEVICES=0,1"}},
+		{constants.PodmanRuntime, 2, []string{"--device", "nvidia.com/gpu=0", "--device", "nvidia.com/gpu=1",
+			"-e", "NVIDIA_VISIBLE_DEVICES=0,1"}},
+		{constants.PodmanRuntime, 0, []string{"-e", "NVIDIA_VISIBLE_DEVICES="}},
+	}
+
+	for _, c := range cases {
+		args := runtimes[c.runtime].GPUArgs(c.gpus)
+		if !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("%v GPUArgs(%v) returned %v, expected %v", c.runtime, c.gpus, args, c.expected)
+		}
+	}
+}
+
+func TestRuntimeCommands(t *testing.T) {
+	log, done := fakeRuntime(t, "4.3.1")
+	defer done()
+
+	SetRuntime(constants.PodmanRuntime)
+	args, command := CommandArgsInit()
+	if command != constants.PodmanRuntime || len(args) != 0 {
+		t.Errorf("CommandArgsInit() returned %v %v, expected podman with no args", command, args)
+	}
+
+	if err := StopContainer("seed-job-1", 5); err != nil {
+		t.Errorf("StopContainer returned an error: %v", err)
+	}
+	logged, _ := ioutil.ReadFile(log)
+	if strings.TrimSpace(string(logged)) != "stop -t 5 seed-job-1" {
+		t.Errorf("StopContainer ran podman %q, expected %q", strings.TrimSpace(string(logged)), "stop -t 5 seed-job-1")
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	cases := []struct {
+		version  string
+		min      string
+		expected bool
+	}{
+		{"1.13.0", "1.13", true},
+		{"1.12.6", "1.13", false},
+		{"17.03.0-ce", "1.13", true},
+		{"1", "1.0", true},
+		{"1.6", "1.6.1", false},
+		{"", "1.0", false},
+	}
+
+	for _, c := range cases {
+		if result := versionAtLeast(c.version, c.min); result != c.expected {
+			t.Errorf("versionAtLeast(%v, %v) returned %v, expected %v", c.version, c.min, result, c.expected)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/constants"
 	"github.com/ngageoint/seed-common/objects"
 	"github.com/ngageoint/seed-common/util"
@@ -16,12 +21,13 @@ import (
 )
 
 type BatchIO struct {
-	Inputs []string
-	Json   []string
-	Outdir string
+	Inputs   []string
+	Json     []string
+	Settings []string
+	Outdir   string
 }
 
-func BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchema string, settings, mounts []string, rmFlag bool) error {
+func BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchema string, settings, mounts []string, rmFlag bool, opts RunOptions) error {
 
 	if imageName == "" {
 		util.PrintUtil("INFO: Image name not specified. Attempting to use manifest: %v\n", manifest)
@@ -36,7 +42,7 @@ func BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchem
 		return errors.New("ERROR: No input image specified.")
 	}
 
-	if exists, err := util.ImageExists(imageName); !exists {
+	if exists, err := imageExists(imageName); !exists {
 		msg := fmt.Sprintf("Unable to find image: %s. Did you specify a valid tag?", imageName)
 		util.PrintUtil("%s\n", msg)
 		return err
@@ -50,6 +56,18 @@ func BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchem
 
 	seed := objects.SeedFromImageLabel(imageName)
 
+	if opts.JobSpec != nil {
+		if err := opts.JobSpec.Validate(&seed, true); err != nil {
+			return err
+		}
+		// the rows are validated as a batch rather than each run
+		opts.JobSpec = nil
+	}
+
+	if opts.Resume && outputDir == "" {
+		return errors.New("ERROR: The output directory of the batch to resume must be specified with -" + constants.JobOutputDirFlag)
+	}
+
 	outdir := getOutputDir(outputDir, imageName)
 
 	var inputs []BatchIO
@@ -69,32 +87,204 @@ func BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchem
 		}
 	}
 
-	bar := pb.StartNew(len(inputs))
-	bar.Output = os.Stderr
-	defer bar.Finish()
+	// secret setting values are redacted from any failure messages
+	secrets, err := LoadSecrets(&seed, opts.SecretsFile)
+	if err != nil {
+		return err
+	}
+	secrets = append(secrets, SecretSettings(&seed, settings)...)
 	for _, in := range inputs {
-		exitCode, err := DockerRun(imageName, manifest, in.Outdir, metadataSchema, in.Inputs, in.Json, settings, mounts, rmFlag, true)
+		secrets = append(secrets, SecretSettings(&seed, in.Settings)...)
+	}
+	WarnShortSecrets(secrets)
 
-		//trim inputs to print only the key values and filenames
-		truncatedInputs := []string{}
-		for _, i := range in.Inputs {
-			begin := strings.Index(i, "=") + 1
-			end := strings.LastIndex(i, "/")
-			truncatedInputs = append(truncatedInputs, i[0:begin]+"..."+i[end:])
+	// record the state of each row in a journal so the batch can be resumed
+	journalPath := filepath.Join(outdir, constants.BatchJournalFile)
+	var previous *BatchJournal
+	if opts.Resume {
+		previous, err = LoadBatchJournal(journalPath)
+		if err != nil {
+			return fmt.Errorf("ERROR: Error reading batch journal %s.\n%v", journalPath, err)
+		}
+		if previous == nil {
+			util.PrintUtil("INFO: No batch journal found in %s; running all jobs\n", outdir)
 		}
+	}
+	journal := NewBatchJournal(journalPath, imageName, redactBatchSettings(inputs, secrets), previous)
+	if err = journal.Save(); err != nil {
+		return fmt.Errorf("ERROR: Error writing batch journal %s.\n%v", journalPath, err)
+	}
 
-		if err != nil {
-			msg := fmt.Sprintf("FAIL: Input = %v \t ExitCode = %d \t Error = %s \n", truncatedInputs, exitCode, err.Error())
-			util.InitPrinter(util.PrintErr, os.Stderr, os.Stderr)
-			util.PrintUtil("%v", msg)
+	// running jobs stop their containers before the journal is saved as they registered later
+	defer cliutil.OnInterrupt(func() {
+		if err := journal.Interrupt(); err != nil {
+			util.PrintUtil("ERROR: Error writing batch journal %s: %s\n", journalPath, err.Error())
+		}
+		util.PrintUtil("INFO: Batch interrupted. Run it again with -%s -%s %s to finish it.\n",
+			constants.ResumeFlag, constants.JobOutputDirFlag, outdir)
+	})()
+
+	var pending []int
+	for i, row := range journal.Rows {
+		if row.Status != RowSucceeded {
+			pending = append(pending, i)
 		}
+	}
+	if skipped := len(inputs) - len(pending); skipped > 0 {
+		util.PrintUtil("INFO: Skipping %d jobs that already succeeded\n", skipped)
+	}
 
-		bar.Increment()
-		time.Sleep(time.Second)
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > len(pending) && len(pending) > 0 {
+		parallel = len(pending)
 	}
 
+	// jobs only start when the cpus and memory they need are free
+	pool := newResourcePool(float64(runtime.NumCPU()), hostMemoryMiB())
+	if parallel > 1 {
+		util.PrintUtil("INFO: Running %d jobs at a time\n", parallel)
+	}
+
+	batchStart := time.Now()
+	bar := pb.StartNew(len(pending))
+	bar.Output = os.Stderr
+	defer bar.Finish()
+
+	// the jobs run quietly so concurrent jobs don't interleave on the console; the
+	// workers print failures themselves
+	util.InitPrinter(util.Quiet, nil, nil)
+	rows := make(chan int)
+	var printMutex sync.Mutex
+	printFail := func(msg string) {
+		printMutex.Lock()
+		fmt.Fprintf(os.Stderr, "%v", RedactSecrets(msg, secrets))
+		printMutex.Unlock()
+	}
+	updateJournal := func(i int, update func(row *JournalRow)) {
+		if err := journal.Update(i, update); err != nil {
+			printFail(fmt.Sprintf("WARNING: Error writing batch journal %s: %s\n", journalPath, err.Error()))
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				in := inputs[i]
+				// the stderr of jobs running at the same time is labelled with their row
+				jobOpts := opts
+				if parallel > 1 {
+					jobOpts.LogPrefix = fmt.Sprintf("[job-%d] ", i+1)
+				}
+				for attempt := 0; attempt <= opts.RetryFailed; attempt++ {
+					if cliutil.Interrupted() {
+						updateJournal(i, func(row *JournalRow) {
+							row.Status = RowInterrupted
+							row.Error = "Interrupted"
+						})
+						break
+					}
+					if attempt > 0 {
+						backoff := retryBackoff(attempt)
+						printFail(fmt.Sprintf("INFO: Retrying Input = %v in %v (retry %d of %d)\n",
+							truncateInputs(in.Inputs), backoff, attempt, opts.RetryFailed))
+						time.Sleep(backoff)
+					}
+
+					updateJournal(i, func(row *JournalRow) {
+						row.Status = RowRunning
+						row.Attempts++
+					})
+
+					start := time.Now()
+					cpus, mem, err := jobResources(&seed, in.Inputs, opts.Resources)
+					result := RunResult{ExitCode: -1}
+					if err == nil {
+						pool.acquire(cpus, mem)
+						// the batch may have been interrupted while waiting for resources
+						if cliutil.Interrupted() {
+							err = errors.New("ERROR: Batch interrupted")
+						} else {
+							result, err = DockerRun(imageName, manifest, in.Outdir, metadataSchema, in.Inputs, in.Json, append(append([]string{}, settings...), in.Settings...), mounts, rmFlag, jobOpts)
+						}
+						pool.release(cpus, mem)
+					}
+
+					updateJournal(i, func(row *JournalRow) {
+						row.ExitCode = result.ExitCode
+						row.TimedOut = result.TimedOut
+						row.Duration = time.Since(start).Seconds()
+						row.JobError = result.JobError
+						row.Validation = result.Validation.Errors()
+						// a rerun into a non-empty directory writes to a new subdirectory of it
+						if result.OutputDir != "" {
+							row.Outdir = result.OutputDir
+						}
+						row.Status = RowSucceeded
+						row.Error = ""
+						if err != nil && cliutil.Interrupted() {
+							row.Status = RowInterrupted
+							row.Error = "Interrupted"
+						} else if err != nil {
+							row.Status = RowFailed
+							row.Error = RedactSecrets(err.Error(), secrets)
+						}
+					})
+
+					if err == nil {
+						break
+					}
+					printFail(fmt.Sprintf("FAIL: Input = %v \t ExitCode = %d \t Error = %s \n", truncateInputs(in.Inputs), result.ExitCode, err.Error()))
+				}
+
+				bar.Increment()
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		if cliutil.Interrupted() {
+			break
+		}
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+
+	util.InitPrinter(util.PrintErr, os.Stderr, os.Stderr)
 	bar.FinishPrint("Batch complete")
-	return err
+
+	report := NewBatchReport(journal, time.Since(batchStart))
+	reportFile, err := WriteBatchReport(report, outdir, opts.Report)
+	if err != nil {
+		return fmt.Errorf("ERROR: Error writing batch report.\n%v", err)
+	}
+	util.PrintUtil("INFO: %d of %d jobs succeeded. Batch report written to %s\n",
+		report.Succeeded, report.Total, reportFile)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("ERROR: %d of %d jobs failed", report.Failed, report.Total)
+	}
+	return nil
+}
+
+//truncateInputs trims inputs to print only the key values and filenames
+func truncateInputs(inputs []string) []string {
+	truncatedInputs := []string{}
+	for _, i := range inputs {
+		begin := strings.Index(i, "=") + 1
+		end := strings.LastIndex(i, "/")
+		if end < begin {
+			end = begin
+		}
+		truncatedInputs = append(truncatedInputs, i[0:begin]+"..."+i[end:])
+	}
+	return truncatedInputs
 }
 
 //PrintBatchUsage prints the seed batch usage arguments, then exits the program
@@ -108,23 +298,66 @@ func PrintBatchUsage() {
 		constants.ShortImgNameFlag, constants.ImgNameFlag)
 	util.PrintUtil("  -%s -%s\t  Manifest file to use if an image name is not specified (default is seed.manifest.json within the current directory).\n",
 		constants.ShortManifestFlag, constants.ManifestFlag)
-	util.PrintUtil("  -%s  -%s \t Optional file specifying input keys and file mapping for batch processing. Supersedes directory flag.\n",
-		constants.ShortBatchFlag, constants.BatchFlag)
+	util.PrintUtil("  -%s  -%s\t YAML or JSON job spec file giving the image or manifest, batch file or directory, settings, mounts, output directory, schema and resources. Flags override values in the file\n",
+		constants.ShortJobSpecFlag, constants.JobSpecFlag)
+	util.PrintUtil("  -%s  -%s \t Optional CSV file specifying input keys and file mapping for batch processing. Supersedes directory flag.\n"+
+		"\t\t Keys prefixed with %s, %s or %s give file inputs, JSON inputs or settings; an %s column names each output directory.\n",
+		constants.ShortBatchFlag, constants.BatchFlag, constants.BatchFilePrefix, constants.BatchJsonPrefix,
+		constants.BatchSettingPrefix, constants.BatchOutdirColumn)
 	util.PrintUtil("  -%s  -%s Alternative to batch file.  Specifies a directory of files to batch process (default is current directory).\n",
 		constants.ShortJobDirectoryFlag, constants.JobDirectoryFlag)
 	util.PrintUtil("  -%s \t\t Automatically remove the container when it exits (docker run --rm)\n",
 		constants.RmFlag)
 	util.PrintUtil("  -%s  -%s \t Specifies the key/value setting values of the seed spec in the format SETTING_KEY=VALUE\n",
 		constants.ShortSettingFlag, constants.SettingFlag)
+	util.PrintUtil("  -%s \t File of SETTING_KEY=VALUE lines for secret settings. Secret settings not given are read from the environment\n",
+		constants.SecretsFlag)
 	util.PrintUtil("  -%s  -%s \t Specifies the key/value mount values of the seed spec in the format MOUNT_KEY=HOST_PATH\n",
 		constants.ShortMountFlag, constants.MountFlag)
 	util.PrintUtil("  -%s  -%s \t Job Output Directory Location\n",
 		constants.ShortJobOutputDirFlag, constants.JobOutputDirFlag)
 	util.PrintUtil("  -%s  -%s \t External Seed metadata schema file; Overrides built in schema to validate side-car metadata files\n",
 		constants.ShortSchemaFlag, constants.SchemaFlag)
+	util.PrintUtil("  -%s  -%s Job timeout in seconds for each run; overrides job.timeout from the seed manifest\n",
+		constants.ShortTimeoutFlag, constants.TimeoutFlag)
+	util.PrintUtil("  -%s  Overrides a scalar resource in the format NAME=VALUE, NAME=min:VALUE, NAME=max:VALUE or NAME=xFACTOR (use NAME 'all' for every resource)\n",
+		constants.ResourcesFlag)
+	util.PrintUtil("  -%s\t Pins each job to its cpus resource with docker --cpuset-cpus instead of limiting with --cpus; not with -%s greater than 1\n",
+		constants.CPUSetFlag, constants.ParallelFlag)
+	util.PrintUtil("  -%s\t Number of jobs to run at once (default 1). Jobs only start when the cpus and mem they require are free\n",
+		constants.ParallelFlag)
+	util.PrintUtil("  -%s\t Resumes the batch in the output directory, skipping jobs that already succeeded\n",
+		constants.ResumeFlag)
+	util.PrintUtil("  -%s\t Number of times to retry failed jobs, waiting longer between each retry (default 0)\n",
+		constants.RetryFailedFlag)
+	util.PrintUtil("  -%s\t Format of the batch report written to the batch output directory: json, csv or junit (default json)\n",
+		constants.ReportFlag)
+	util.PrintUtil("  -%s Whether to warn (default), fail or skip the check when an input file's media type isn't in the seed manifest\n",
+		constants.MediaTypesFlag)
+	util.PrintUtil("  -%s Runs the jobs without restoring or storing their output in the result cache enabled by %s\n",
+		constants.NoCacheFlag, constants.CacheDirEnv)
+	util.PrintUtil("  -%s Prefixes each line of the jobs' %s and %s with the time it was written\n",
+		constants.LogTimestampsFlag, constants.StdoutLogFile, constants.StderrLogFile)
+	util.PrintUtil("\nEach job's stdout and stderr are written to %s and %s in its output directory.\n",
+		constants.StdoutLogFile, constants.StderrLogFile)
+	util.PrintUtil("The state of each job is recorded in %s in the batch output directory.\n", constants.BatchJournalFile)
 	return
 }
 
+//redactBatchSettings returns a copy of the batch rows with secret setting values
+// redacted so they are never written to the batch journal
+func redactBatchSettings(inputs []BatchIO, secrets []string) []BatchIO {
+	redacted := make([]BatchIO, len(inputs))
+	for i, in := range inputs {
+		redacted[i] = in
+		redacted[i].Settings = nil
+		for _, s := range in.Settings {
+			redacted[i].Settings = append(redacted[i].Settings, RedactSecrets(s, secrets))
+		}
+	}
+	return redacted
+}
+
 func getOutputDir(outputDir, imageName string) string {
 	if outputDir == "" {
 		outputDir = "batch-" + imageName + "-" + time.Now().Format(time.RFC3339)
@@ -185,7 +418,7 @@ func ProcessDirectory(seed objects.Seed, batchDir, outdir string) ([]BatchIO, er
 		fileInputs := []string{}
 		jsonInputs := []string{}
 		fileInputs = append(fileInputs, key+"="+filePath)
-		row := BatchIO{fileInputs, jsonInputs, fileDir}
+		row := BatchIO{Inputs: fileInputs, Json: jsonInputs, Outdir: fileDir}
 		batchIO = append(batchIO, row)
 	}
 
@@ -194,25 +427,140 @@ func ProcessDirectory(seed objects.Seed, batchDir, outdir string) ([]BatchIO, er
 	return batchIO, err
 }
 
+//ProcessBatchFile reads the rows of an RFC 4180 CSV batch file. The header names the
+// input of each column: a plain or file: prefixed name is a file input, json: a JSON
+// input and setting: a setting. An optional outdir column names each row's output
+// directory. Empty values are left out of the row.
 func ProcessBatchFile(seed objects.Seed, batchFile, outdir string) ([]BatchIO, error) {
-	lines, err := util.ReadLinesFromFile(batchFile)
+	file, err := os.Open(batchFile)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// rows with the wrong number of values are reported below with their line number
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
 
-	if len(lines) == 0 {
+	header, err := reader.Read()
+	if err == io.EOF {
 		return nil, errors.New("ERROR: Empty batch file")
+	} else if err != nil {
+		return nil, fmt.Errorf("ERROR: Error parsing batch file: %v", err)
 	}
 
-	keys := strings.Split(lines[0], ",")
-	extraKeys := keys
-
-	if len(keys) == 0 || len(keys[0]) == 0 {
+	// blank lines are skipped by the reader so the keys must be found on line 1
+	if line, _ := reader.FieldPos(0); line != 1 || len(strings.TrimSpace(header[0])) == 0 {
 		return nil, errors.New("ERROR: Empty keys list on first line of batch file.")
 	}
 
+	columns, err := batchColumns(seed, header)
+	if err != nil {
+		return nil, err
+	}
+
+	batchIO := []BatchIO{}
+	outdirLines := map[string]int{}
+	for i := 1; ; i++ {
+		values, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("ERROR: Error parsing batch file: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		if len(values) != len(columns) {
+			return nil, fmt.Errorf("ERROR: Batch file line %d has %d values, expected %d to match the keys on line 1",
+				line, len(values), len(columns))
+		}
+
+		row := BatchIO{Inputs: []string{}, Json: []string{}}
+		inputNames := fmt.Sprintf("%d", i)
+		outdirName := ""
+		for j, value := range values {
+			if value == "" {
+				continue
+			}
+			c := columns[j]
+			switch c.kind {
+			case constants.BatchFilePrefix:
+				row.Inputs = append(row.Inputs, c.name+"="+value)
+				inputNames += "-" + filepath.Base(value)
+			case constants.BatchJsonPrefix:
+				row.Json = append(row.Json, c.name+"="+value)
+			case constants.BatchSettingPrefix:
+				row.Settings = append(row.Settings, c.name+"="+value)
+			case constants.BatchOutdirColumn:
+				if value != filepath.Base(value) || value == "." || value == ".." {
+					return nil, fmt.Errorf("ERROR: Batch file line %d: output directory name %s must not be a path",
+						line, value)
+				}
+				outdirName = value
+			}
+		}
+
+		if outdirName == "" {
+			outdirName = inputNames
+		}
+		if prev, ok := outdirLines[outdirName]; ok {
+			return nil, fmt.Errorf("ERROR: Batch file line %d: output directory name %s is already used on line %d",
+				line, outdirName, prev)
+		}
+		outdirLines[outdirName] = line
+
+		row.Outdir = filepath.Join(outdir, outdirName)
+		batchIO = append(batchIO, row)
+	}
+
+	util.PrintUtil("Batch Input = %s \t", batchFile)
+	util.PrintUtil("Batch Output Dir = %s \n", outdir)
+
+	return batchIO, nil
+}
+
+//batchColumn is a column of a batch file
+type batchColumn struct {
+	// kind is the column prefix: file:, json:, setting:, or outdir for the output directory column
+	kind string
+	name string
+}
+
+//batchColumns parses the header of a batch file and checks its keys against the inputs
+// and settings in the seed manifest
+func batchColumns(seed objects.Seed, header []string) ([]batchColumn, error) {
+	var columns []batchColumn
+	keys := map[string][]string{}
+	seen := map[string]bool{}
+	for _, h := range header {
+		h = strings.TrimSpace(h)
+		c := batchColumn{kind: constants.BatchFilePrefix, name: h}
+		if h == constants.BatchOutdirColumn {
+			c.kind = h
+		} else if i := strings.Index(h, ":"); i >= 0 {
+			c.kind, c.name = h[:i+1], h[i+1:]
+			if c.kind != constants.BatchFilePrefix && c.kind != constants.BatchJsonPrefix &&
+				c.kind != constants.BatchSettingPrefix {
+				return nil, fmt.Errorf("ERROR: Unknown batch file key prefix %s. Supported prefixes are %s, %s and %s",
+					c.kind, constants.BatchFilePrefix, constants.BatchJsonPrefix, constants.BatchSettingPrefix)
+			}
+		}
+
+		if c.name == "" {
+			return nil, errors.New("ERROR: Empty key on first line of batch file.")
+		}
+		if seen[c.kind+c.name] {
+			return nil, fmt.Errorf("ERROR: Batch file key %s is given more than once", h)
+		}
+		seen[c.kind+c.name] = true
+		keys[c.kind] = append(keys[c.kind], c.name)
+		columns = append(columns, c)
+	}
+
+	extraKeys := keys[constants.BatchFilePrefix]
 	for _, f := range seed.Job.Interface.Inputs.Files {
-		hasKey := util.ContainsString(keys, f.Name)
+		hasKey := util.ContainsString(keys[constants.BatchFilePrefix], f.Name)
 		if f.Required && !hasKey {
 			msg := fmt.Sprintf("ERROR: Batch file is missing required key %v", f.Name)
 			return nil, errors.New(msg)
@@ -222,34 +570,33 @@ func ProcessBatchFile(seed objects.Seed, batchFile, outdir string) ([]BatchIO, e
 		extraKeys = util.RemoveString(extraKeys, f.Name)
 	}
 
-	if len(extraKeys) > 0 {
-		msg := fmt.Sprintf("WARN: These input keys don't match any specified keys in the Seed manifest: %v\n", extraKeys)
-		util.PrintUtil(msg)
+	extraJson := keys[constants.BatchJsonPrefix]
+	for _, j := range seed.Job.Interface.Inputs.Json {
+		hasKey := util.ContainsString(keys[constants.BatchJsonPrefix], j.Name)
+		if j.Required && !hasKey {
+			msg := fmt.Sprintf("ERROR: Batch file is missing required key %v%v", constants.BatchJsonPrefix, j.Name)
+			return nil, errors.New(msg)
+		}
+		extraJson = util.RemoveString(extraJson, j.Name)
+	}
+	for _, k := range extraJson {
+		extraKeys = append(extraKeys, constants.BatchJsonPrefix+k)
 	}
 
-	batchIO := []BatchIO{}
-	for i, line := range lines {
-		if i == 0 || len(line) == 0 {
-			continue
+	for _, k := range keys[constants.BatchSettingPrefix] {
+		found := false
+		for _, s := range seed.Job.Interface.Settings {
+			found = found || s.Name == k
 		}
-		values := strings.Split(line, ",")
-		fileInputs := []string{}
-		jsonInputs := []string{}
-		inputNames := fmt.Sprintf("%d", i)
-		for j, file := range values {
-			if j > len(keys) {
-				util.PrintUtil("WARN: More files provided than keys")
-			}
-			fileInputs = append(fileInputs, keys[j]+"="+file)
-			inputNames += "-" + filepath.Base(file)
+		if !found {
+			extraKeys = append(extraKeys, constants.BatchSettingPrefix+k)
 		}
-		fileDir := filepath.Join(outdir, inputNames)
-		row := BatchIO{fileInputs, jsonInputs, fileDir}
-		batchIO = append(batchIO, row)
 	}
 
-	util.PrintUtil("Batch Input = %s \t", batchFile)
-	util.PrintUtil("Batch Output Dir = %s \n", outdir)
+	if len(extraKeys) > 0 {
+		msg := fmt.Sprintf("WARN: These input keys don't match any specified keys in the Seed manifest: %v\n", extraKeys)
+		util.PrintUtil(msg)
+	}
 
-	return batchIO, err
+	return columns, nil
 }
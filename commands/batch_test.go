@@ -2,9 +2,13 @@ package commands
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ngageoint/seed-common/objects"
 	"github.com/ngageoint/seed-common/util"
@@ -23,10 +27,10 @@ func TestProcessDirectory(t *testing.T) {
 		expectedErrorMsg string
 	}{
 		{"../testdata", "../testdata/test-extract", "../examples/extractor/seed.manifest.json",
-			"[{[ZIP=../testdata/batch-test.csv] [] ../testdata/test-extract/batch-test.csv} " +
-				"{[ZIP=../testdata/empty-batch.csv] [] ../testdata/test-extract/empty-batch.csv} " +
-				"{[ZIP=../testdata/missing-keys.csv] [] ../testdata/test-extract/missing-keys.csv} " +
-				"{[ZIP=../testdata/seed-scale.zip] [] ../testdata/test-extract/seed-scale.zip}]",
+			"[{[ZIP=../testdata/batch-test.csv] [] [] ../testdata/test-extract/batch-test.csv} " +
+				"{[ZIP=../testdata/empty-batch.csv] [] [] ../testdata/test-extract/empty-batch.csv} " +
+				"{[ZIP=../testdata/missing-keys.csv] [] [] ../testdata/test-extract/missing-keys.csv} " +
+				"{[ZIP=../testdata/seed-scale.zip] [] [] ../testdata/test-extract/seed-scale.zip}]",
 			""},
 		{"../testdata", "../testdata/test-multiple", "../testdata/multiple-required-inputs/seed.manifest.json",
 			"[]", "ERROR: Multiple required inputs are not supported when batch processing directories."},
@@ -60,9 +64,9 @@ func TestProcessBatchFile(t *testing.T) {
 		expectedErrorMsg string
 	}{
 		{"../testdata/batch-test.csv", "../testdata/test-extract-file", "../examples/extractor/seed.manifest.json",
-			"[{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test1.zip] [] ../testdata/test-extract-file/1-test1.zip} " +
-				"{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test2.zip] [] ../testdata/test-extract-file/2-test2.zip} " +
-				"{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test3.zip] [] ../testdata/test-extract-file/3-test3.zip}]",
+			"[{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test1.zip] [] [] ../testdata/test-extract-file/1-test1.zip} " +
+				"{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test2.zip] [] [] ../testdata/test-extract-file/2-test2.zip} " +
+				"{[ZIP=/home/jtobe/go/src/github.com/ngageoint/seed-cli/testdata/test3.zip] [] [] ../testdata/test-extract-file/3-test3.zip}]",
 			""},
 		{"../testdata/empty-batch.csv", "../testdata/test-empty", "../testdata/multiple-required-inputs/seed.manifest.json",
 			"[]", "ERROR: Empty batch file"},
@@ -86,3 +90,272 @@ func TestProcessBatchFile(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessBatchFileColumns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-batch-file-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	seed := objects.Seed{}
+	seed.Job.Interface.Inputs.Files = []objects.InFile{{Name: "ZIP", Required: true}, {Name: "AUX"}}
+	seed.Job.Interface.Inputs.Json = []objects.InJson{{Name: "COUNT", Type: "integer"}}
+	seed.Job.Interface.Settings = []objects.Setting{{Name: "MODE"}}
+
+	cases := []struct {
+		contents         string
+		expected         string
+		expectedErrorMsg string
+	}{
+		{"ZIP,file:AUX,json:COUNT,setting:MODE\n\"a,1.zip\",b.txt,3,fast\n\"say \"\"hi\"\".zip\",,,\n",
+			"[{[ZIP=a,1.zip AUX=b.txt] [COUNT=3] [MODE=fast] out/1-a,1.zip-b.txt} {[ZIP=say \"hi\".zip] [] [] out/2-say \"hi\".zip}]", ""},
+		{"file:ZIP,outdir\na.zip,first\nb.zip,\n", "[{[ZIP=a.zip] [] [] out/first} {[ZIP=b.zip] [] [] out/2-b.zip}]", ""},
+		{"ZIP\r\na.zip\r\n\r\nb.zip\r\n", "[{[ZIP=a.zip] [] [] out/1-a.zip} {[ZIP=b.zip] [] [] out/2-b.zip}]", ""},
+		{"ZIP,AUX\na.zip,b.txt\nc.zip,d.txt,e.txt\n", "[]", "Batch file line 3 has 3 values, expected 2"},
+		{"ZIP\na.zip\n\nb.zip,c.zip\n", "[]", "Batch file line 4 has 2 values, expected 1"},
+		{"ZIP,outdir\na.zip,same\nb.zip,same\n", "[]", "Batch file line 3: output directory name same is already used on line 2"},
+		{"ZIP,outdir\na.zip,../up\n", "[]", "Batch file line 2: output directory name ../up must not be a path"},
+		{"ZIP\n\"a.zip\n", "[]", "ERROR: Error parsing batch file: parse error on line 2"},
+		{"AUX\nb.txt\n", "[]", "Batch file is missing required key ZIP"},
+		{"ZIP,env:MODE\na.zip,fast\n", "[]", "Unknown batch file key prefix env:"},
+		{"ZIP,ZIP\na.zip,b.zip\n", "[]", "Batch file key ZIP is given more than once"},
+	}
+
+	for _, c := range cases {
+		batchFile := filepath.Join(dir, "batch.csv")
+		ioutil.WriteFile(batchFile, []byte(c.contents), 0644)
+		out, err := ProcessBatchFile(seed, batchFile, "out")
+		outstr := fmt.Sprintf("%v", out)
+		if outstr != c.expected {
+			t.Errorf("ProcessBatchFile(%q) == %v, expected %v", c.contents, outstr, c.expected)
+		}
+		if c.expectedErrorMsg == "" && err != nil {
+			t.Errorf("ProcessBatchFile(%q) returned an error: %v", c.contents, err)
+		} else if c.expectedErrorMsg != "" && (err == nil || !strings.Contains(err.Error(), c.expectedErrorMsg)) {
+			t.Errorf("ProcessBatchFile(%q) returned error %v, expected %v", c.contents, err, c.expectedErrorMsg)
+		}
+	}
+}
+
+func TestJobResources(t *testing.T) {
+	cases := []struct {
+		manifestFile     string
+		inputs           []string
+		overrides        []string
+		expectedCPUs     float64
+		expectedMem      float64
+		expectedErrorMsg string
+	}{
+		{"../examples/addition-job/seed.manifest.json", []string{}, []string{}, 0.1, 16, ""},
+		{"../examples/extractor/seed.manifest.json", []string{"ZIP=../testdata/seed-scale.zip"}, []string{}, 1, 16, ""},
+		{"../examples/addition-job/seed.manifest.json", []string{}, []string{"mem=x4", "cpus=min:0.5"}, 0.5, 64, ""},
+		{"../testdata/no-inputs/seed.manifest.json", []string{}, []string{"mem"}, 0, 0, "should be specified in NAME=VALUE format"},
+	}
+
+	for _, c := range cases {
+		seed := objects.SeedFromManifestFile(c.manifestFile)
+		cpus, mem, err := jobResources(&seed, c.inputs, c.overrides)
+		if c.expectedErrorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.expectedErrorMsg) {
+				t.Errorf("jobResources(%q, %v) returned error %v, expected %v", c.manifestFile, c.overrides, err, c.expectedErrorMsg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("jobResources(%q, %v) returned an error: %v", c.manifestFile, c.overrides, err)
+		}
+		if cpus != c.expectedCPUs || mem != c.expectedMem {
+			t.Errorf("jobResources(%q, %v) == %v, %v, expected %v, %v", c.manifestFile, c.overrides, cpus, mem, c.expectedCPUs, c.expectedMem)
+		}
+	}
+}
+
+func TestResourcePool(t *testing.T) {
+	cases := []struct {
+		cpus        float64
+		mem         float64
+		jobCPUs     float64
+		jobMem      float64
+		jobs        int
+		maxExpected int
+	}{
+		{4, 0, 1, 0, 8, 4},
+		{4, 1024, 1, 512, 8, 2},
+		{0, 0, 1, 512, 8, 8},
+		{2, 0, 4, 0, 3, 1},
+	}
+
+	for _, c := range cases {
+		pool := newResourcePool(c.cpus, c.mem)
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		running, maxRunning := 0, 0
+		for i := 0; i < c.jobs; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pool.acquire(c.jobCPUs, c.jobMem)
+				mutex.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mutex.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				pool.release(c.jobCPUs, c.jobMem)
+			}()
+		}
+		wg.Wait()
+
+		if maxRunning > c.maxExpected {
+			t.Errorf("resourcePool(%v cpus, %v mem) ran %v jobs of %v cpus, %v mem at once, expected at most %v",
+				c.cpus, c.mem, maxRunning, c.jobCPUs, c.jobMem, c.maxExpected)
+		}
+	}
+}
+
+func TestBatchJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-journal-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seed.batch.json")
+
+	inputs := []BatchIO{
+		{Inputs: []string{"ZIP=a.zip"}, Json: []string{}, Outdir: filepath.Join(dir, "a.zip")},
+		{Inputs: []string{"ZIP=b.zip"}, Json: []string{}, Outdir: filepath.Join(dir, "b.zip")},
+		{Inputs: []string{"ZIP=c.zip"}, Json: []string{}, Outdir: filepath.Join(dir, "c.zip")},
+	}
+
+	previous, err := LoadBatchJournal(path)
+	if previous != nil || err != nil {
+		t.Errorf("LoadBatchJournal of a missing journal returned %v, %v, expected nil", previous, err)
+	}
+
+	journal := NewBatchJournal(path, "my-job-0.1.0-seed:1.0.0", inputs, nil)
+	journal.Update(0, func(row *JournalRow) { row.Status = RowSucceeded })
+	journal.Update(1, func(row *JournalRow) {
+		row.Status = RowFailed
+		row.ExitCode = 3
+		row.Attempts = 2
+	})
+
+	previous, err = LoadBatchJournal(path)
+	if err != nil {
+		t.Fatalf("LoadBatchJournal returned an error: %v", err)
+	}
+	statuses := []string{}
+	for _, row := range previous.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	if fmt.Sprintf("%v", statuses) != "[succeeded failed pending]" {
+		t.Errorf("LoadBatchJournal returned rows with statuses %v, expected [succeeded failed pending]", statuses)
+	}
+	if previous.Rows[1].ExitCode != 3 || previous.Rows[1].Attempts != 2 {
+		t.Errorf("LoadBatchJournal returned failed row %+v, expected exit code 3 after 2 attempts", previous.Rows[1])
+	}
+
+	// resuming keeps the rows that succeeded and reruns the rest
+	resumed := NewBatchJournal(path, "my-job-0.1.0-seed:1.0.0", inputs, previous)
+	statuses = []string{}
+	for _, row := range resumed.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	if fmt.Sprintf("%v", statuses) != "[succeeded pending pending]" {
+		t.Errorf("NewBatchJournal resumed rows with statuses %v, expected [succeeded pending pending]", statuses)
+	}
+
+	// an interrupted batch records the rows that were running
+	resumed.Update(1, func(row *JournalRow) { row.Status = RowRunning })
+	if err := resumed.Interrupt(); err != nil {
+		t.Errorf("Interrupt returned an error: %v", err)
+	}
+	previous, _ = LoadBatchJournal(path)
+	if previous.Rows[1].Status != RowInterrupted || previous.Rows[2].Status != RowPending {
+		t.Errorf("Interrupt saved rows %+v, expected the running row to be interrupted", previous.Rows)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	cases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, time.Minute},
+	}
+
+	for _, c := range cases {
+		if backoff := retryBackoff(c.attempt); backoff != c.expected {
+			t.Errorf("retryBackoff(%v) == %v, expected %v", c.attempt, backoff, c.expected)
+		}
+	}
+}
+
+func TestBatchReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	journal := &BatchJournal{Image: "my-job-0.1.0-seed:1.0.0", Rows: []JournalRow{
+		{Inputs: []string{"INPUT_FILE=a.txt"}, Outdir: "out/a", Status: RowSucceeded, Duration: 1.5},
+		{Inputs: []string{"INPUT_FILE=b.txt"}, Outdir: "out/b", Status: RowFailed, ExitCode: 2,
+			JobError:   &JobError{Code: 2, Name: "bad-input", Title: "Bad Input", Category: "data"},
+			Validation: []string{"No output files found matching OUTPUT_FILE"}},
+		{Inputs: []string{"INPUT_FILE=c.txt"}, Outdir: "out/c", Status: RowPending},
+	}}
+
+	report := NewBatchReport(journal, 3*time.Second)
+	if report.Total != 3 || report.Succeeded != 1 || report.Failed != 2 || report.Duration != 3 {
+		t.Errorf("NewBatchReport returned %v total, %v succeeded, %v failed in %vs, expected 3, 1 and 2 in 3s",
+			report.Total, report.Succeeded, report.Failed, report.Duration)
+	}
+
+	cases := []struct {
+		format   string
+		file     string
+		contains []string
+		errStr   string
+	}{
+		{"", "seed.batch.report.json", []string{`"failed": 2`, `"name": "bad-input"`, `"category": "data"`}, ""},
+		{ReportCSV, "seed.batch.report.csv", []string{"inputs,json,outdir,status,exitCode,timedOut",
+			"INPUT_FILE=b.txt,,out/b,failed,2,false,bad-input,Bad Input,data,No output files found matching OUTPUT_FILE"}, ""},
+		{ReportJUnit, "seed.batch.report.xml", []string{`<testsuite name="my-job-0.1.0-seed:1.0.0" tests="3" failures="2"`,
+			`type="data"`, "Job did not run (pending)", "Validation: No output files found matching OUTPUT_FILE"}, ""},
+		{"yaml", "", nil, "Unknown report format yaml"},
+	}
+
+	for _, c := range cases {
+		file, err := WriteBatchReport(report, dir, c.format)
+		if c.errStr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errStr) {
+				t.Errorf("WriteBatchReport(%v) returned error %v, expected %v", c.format, err, c.errStr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("WriteBatchReport(%v) returned an error: %v", c.format, err)
+			continue
+		}
+		if file != filepath.Join(dir, c.file) {
+			t.Errorf("WriteBatchReport(%v) wrote %v, expected %v", c.format, file, c.file)
+		}
+		data, _ := ioutil.ReadFile(file)
+		for _, s := range c.contains {
+			if !strings.Contains(string(data), s) {
+				t.Errorf("WriteBatchReport(%v) report does not contain %q:\n%s", c.format, s, data)
+			}
+		}
+	}
+}
@@ -1,17 +1,12 @@
 package commands
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
-	"io"
 	"os"
-	"os/exec"
-	"strings"
-	"time"
 
 	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-cli/dockerapi"
 	common_const "github.com/ngageoint/seed-common/constants"
 	"github.com/ngageoint/seed-common/objects"
 	"github.com/ngageoint/seed-common/util"
@@ -19,20 +14,20 @@ import (
 
 //DockerBuild Builds the docker image with the given image tag.
 func DockerBuild(jobDirectory, version, username, password, manifest, dockerfile, cacheFrom string, warnAsError bool) (string, error) {
+	var auth *dockerapi.AuthConfig
 	if username != "" {
-		//set config dir so we don't stomp on other users' logins with sudo
-		configDir := common_const.DockerConfigDir + time.Now().Format(time.RFC3339)
-		os.Setenv(common_const.DockerConfigKey, configDir)
-		defer util.RemoveAllFiles(configDir)
-		defer os.Unsetenv(common_const.DockerConfigKey)
-
 		registry, err := util.DockerfileBaseRegistry(jobDirectory)
 		if err != nil {
 			util.PrintUtil("Error getting registry from dockerfile: %s\n", err.Error())
 		}
-		err = util.Login(registry, username, password)
-		if err != nil {
-			util.PrintUtil("Error calling docker login: %s\n", err.Error())
+		if cliutil.DockerClient() != nil {
+			auth = registryAuth(registry, username, password)
+		} else {
+			cleanup, err := registryLogin(registry, username, password)
+			defer cleanup()
+			if err != nil {
+				util.PrintUtil("Error calling docker login: %s\n", err.Error())
+			}
 		}
 	}
 
@@ -68,17 +63,6 @@ func DockerBuild(jobDirectory, version, username, password, manifest, dockerfile
 
 	// Build Docker image
 	util.PrintUtil("INFO: Building %s\n", imageName)
-	var buildArgs, dockerCommand = cliutil.DockerCommandArgsInit()
-	buildArgs = append(buildArgs, "build")
-	// docker doesn't care about validating the cache-from image
-	if cacheFrom != "" {
-		buildArgs = append(buildArgs, "--cache-from")
-		buildArgs = append(buildArgs, cacheFrom)
-	}
-
-	buildArgs = append(buildArgs, "-t")
-	buildArgs = append(buildArgs, imageName)
-
 	util.PrintUtil("dockerfile: %s\n", dockerfile)
 	if dockerfile != "." {
 		dfile := util.GetFullPath(dockerfile, "")
@@ -86,42 +70,11 @@ func DockerBuild(jobDirectory, version, username, password, manifest, dockerfile
 			util.PrintUtil("ERROR: Dockerfile not found. %s\n", err.Error())
 			return imageName, err
 		}
-		buildArgs = append(buildArgs, "-f")
-		buildArgs = append(buildArgs, dfile)
-	}
-
-	buildArgs = append(buildArgs, util.GetFullPath(jobDirectory, ""))
-
-	if util.DockerVersionHasLabel() {
-		// Set the seed.manifest.json contents as an image label
-		label := "com.ngageoint.seed.manifest=" + objects.GetManifestLabel(seedFileName)
-		buildArgs = append(buildArgs, "--label", label)
 	}
 
-	util.PrintUtil("INFO: Running Docker command:\n%s %s\n", dockerCommand, strings.Join(buildArgs, " "))
-
-	cmd := exec.Command(dockerCommand, buildArgs...)
-	var errs bytes.Buffer
-	if util.StdErr != nil {
-		cmd.Stderr = io.MultiWriter(util.StdErr, &errs)
-	} else {
-		cmd.Stderr = &errs
-	}
-	cmd.Stdout = util.StdErr
-
-	// Run docker build
-	if err := cmd.Run(); err != nil {
-		util.PrintUtil("ERROR: Error executing docker build. %s\n",
-			err.Error())
-		return imageName, err
-	}
-
-	// check for errors on stderr
-	if errs.String() != "" {
-		util.PrintUtil("ERROR: Error building image '%s':\n%s\n",
-			imageName, errs.String())
+	if err = buildImage(imageName, jobDirectory, dockerfile, cacheFrom, seedFileName, auth); err != nil {
 		util.PrintUtil("Exiting seed...\n")
-		return imageName, errors.New(errs.String())
+		return imageName, err
 	}
 
 	inputStr := ""
@@ -0,0 +1,365 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//CacheEntry is the output of a successful run stored in the result cache
+type CacheEntry struct {
+	Key      string    `json:"key"`
+	Image    string    `json:"image"`
+	ImageID  string    `json:"imageId"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"lastUsed"`
+
+	// Size of the cached output in bytes
+	Size int64 `json:"size"`
+
+	dir string
+}
+
+//cacheEntryFile is the name of the file describing a cache entry in its directory
+const cacheEntryFile = "entry.json"
+
+//cacheOutputDir is the name of the directory holding the cached output in an entry's directory
+const cacheOutputDir = "output"
+
+//CacheDir returns the directory of the result cache, empty if the cache isn't enabled
+func CacheDir() string {
+	return os.Getenv(constants.CacheDirEnv)
+}
+
+//CacheSizeLimit returns the size limit of the result cache in bytes
+func CacheSizeLimit() (int64, error) {
+	limit := float64(constants.DefaultCacheSize)
+	if value := os.Getenv(constants.CacheSizeEnv); value != "" {
+		var err error
+		limit, err = strconv.ParseFloat(value, 64)
+		if err != nil || limit < 0 {
+			return 0, fmt.Errorf("ERROR: %s must be a size in MiB, not %s", constants.CacheSizeEnv, value)
+		}
+	}
+	return int64(limit * 1024 * 1024), nil
+}
+
+//CacheMounts returns whether runs with mounts use the result cache
+func CacheMounts() bool {
+	return os.Getenv(constants.CacheMountsEnv) != ""
+}
+
+//ResultCacheKey returns the cache key of a run: a hash of the image ID, the name and
+// SHA-256 hash of each input file, the JSON inputs, the non-secret settings, the mount
+// paths and the metadata schema the output is validated against. Returns an empty key if
+// the run can't be cached because the image or an input file couldn't be read, or it has
+// mounts, whose contents can change under the same path, and CacheMounts is false.
+func ResultCacheKey(record RunRecord, jsonInputs []string, metadataSchema string) string {
+	if record.ImageID == "" || (len(record.Mounts) > 0 && !CacheMounts()) {
+		return ""
+	}
+
+	var inputs []string
+	for _, in := range record.Inputs {
+		if in.Error != "" {
+			return ""
+		}
+		inputs = append(inputs, in.Name+"="+filepath.Base(in.Path)+":"+in.SHA256)
+	}
+
+	var jsonValues []string
+	for key, value := range inputMap(jsonInputs, true) {
+		// JSON inputs given as files are keyed by their contents
+		if data, err := ioutil.ReadFile(util.GetFullPath(value, "")); err == nil {
+			value = string(data)
+		}
+		jsonValues = append(jsonValues, key+"="+value)
+	}
+
+	var settings []string
+	for name, value := range record.Settings {
+		if value != RedactedValue {
+			settings = append(settings, name+"="+value)
+		}
+	}
+
+	var mounts []string
+	for name, path := range record.Mounts {
+		mounts = append(mounts, name+"="+path)
+	}
+
+	// a schema file is keyed by its contents
+	schema := metadataSchema
+	if data, err := ioutil.ReadFile(util.GetFullPath(metadataSchema, "")); metadataSchema != "" && err == nil {
+		schema = string(data)
+	}
+
+	for _, values := range [][]string{inputs, jsonValues, settings, mounts} {
+		sort.Strings(values)
+	}
+	data, _ := json.Marshal([]interface{}{record.ImageID, inputs, jsonValues, settings, mounts, schema})
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+//lookupCache returns the cache entry with the given key if there is one
+func lookupCache(cacheDir, key string) (*CacheEntry, bool) {
+	if cacheDir == "" || key == "" {
+		return nil, false
+	}
+	entry, err := readCacheEntry(filepath.Join(cacheDir, key))
+	return entry, err == nil
+}
+
+//restoreCache copies the cached output of entry into outDir and marks it as used
+func restoreCache(entry *CacheEntry, outDir string) error {
+	if err := copyDir(filepath.Join(entry.dir, cacheOutputDir), outDir); err != nil {
+		return err
+	}
+	entry.LastUsed = time.Now()
+	return writeCacheEntry(entry)
+}
+
+//storeCache copies the output in outDir into the cache under key, then evicts the
+// least recently used entries until the cache is within its size limit
+func storeCache(cacheDir, key string, record RunRecord, outDir string) error {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return err
+	}
+	temp, err := ioutil.TempDir(cacheDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temp)
+
+	if err := copyDir(outDir, filepath.Join(temp, cacheOutputDir)); err != nil {
+		return err
+	}
+	// the run record describes the run that made the output, not a later run restoring it
+	os.Remove(filepath.Join(temp, cacheOutputDir, constants.RunRecordFile))
+
+	now := time.Now()
+	entry := &CacheEntry{Key: key, Image: record.Image, ImageID: record.ImageID, Created: now, LastUsed: now,
+		Size: dirSize(filepath.Join(temp, cacheOutputDir)), dir: temp}
+	if err := writeCacheEntry(entry); err != nil {
+		return err
+	}
+	// another job may have stored the same output first
+	if err := os.Rename(temp, filepath.Join(cacheDir, key)); err != nil && !os.IsExist(err) {
+		if _, statErr := os.Stat(filepath.Join(cacheDir, key)); statErr != nil {
+			return err
+		}
+	}
+
+	limit, err := CacheSizeLimit()
+	if err != nil {
+		return err
+	}
+	_, err = PruneCache(cacheDir, limit)
+	return err
+}
+
+//ListCache returns the entries in the cache, most recently used first
+func ListCache(cacheDir string) ([]CacheEntry, error) {
+	files, err := ioutil.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, f := range files {
+		if !f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+		if entry, err := readCacheEntry(filepath.Join(cacheDir, f.Name())); err == nil {
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+//PruneCache removes the least recently used entries from the cache until its total
+// size is at most maxSize bytes. Returns the removed entries.
+func PruneCache(cacheDir string, maxSize int64) ([]CacheEntry, error) {
+	entries, err := ListCache(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	var removed []CacheEntry
+	for i := len(entries) - 1; i >= 0 && total > maxSize; i-- {
+		if err := os.RemoveAll(entries[i].dir); err != nil {
+			return removed, err
+		}
+		total -= entries[i].Size
+		removed = append(removed, entries[i])
+	}
+	return removed, nil
+}
+
+//CacheList prints the entries in the result cache
+func CacheList() error {
+	cacheDir := CacheDir()
+	if cacheDir == "" {
+		return errors.New("ERROR: The result cache is not enabled. Set " + constants.CacheDirEnv + " to the cache directory.")
+	}
+	entries, err := ListCache(cacheDir)
+	if err != nil {
+		return fmt.Errorf("ERROR: Error reading result cache %s.\n%v", cacheDir, err)
+	}
+
+	var total int64
+	fmt.Fprintf(os.Stdout, "%-14s %-40s %12s  %s\n", "KEY", "IMAGE", "SIZE (MiB)", "LAST USED")
+	for _, e := range entries {
+		fmt.Fprintf(os.Stdout, "%-14s %-40s %12.2f  %s\n", e.Key[:12], e.Image, float64(e.Size)/(1024*1024),
+			e.LastUsed.Format(time.RFC3339))
+		total += e.Size
+	}
+	limit, _ := CacheSizeLimit()
+	util.PrintUtil("%d entries, %.2f MiB of %.2f MiB\n", len(entries), float64(total)/(1024*1024),
+		float64(limit)/(1024*1024))
+	return nil
+}
+
+//CachePrune removes the least recently used entries from the result cache until it is
+// at most maxSize MiB. A negative maxSize prunes to the configured cache size limit.
+func CachePrune(maxSize float64) error {
+	cacheDir := CacheDir()
+	if cacheDir == "" {
+		return errors.New("ERROR: The result cache is not enabled. Set " + constants.CacheDirEnv + " to the cache directory.")
+	}
+	limit := int64(maxSize * 1024 * 1024)
+	if maxSize < 0 {
+		var err error
+		if limit, err = CacheSizeLimit(); err != nil {
+			return err
+		}
+	}
+
+	removed, err := PruneCache(cacheDir, limit)
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+	}
+	util.PrintUtil("INFO: Removed %d cache entries, freeing %.2f MiB\n", len(removed), float64(freed)/(1024*1024))
+	if err != nil {
+		return fmt.Errorf("ERROR: Error pruning result cache %s.\n%v", cacheDir, err)
+	}
+	return nil
+}
+
+//PrintCacheUsage prints the seed cache usage arguments, then exits the program
+func PrintCacheUsage() {
+	util.PrintUtil("\nUsage:\tseed cache ls\n\tseed cache prune [-%s MiB | -%s]\n", constants.MaxSizeFlag, constants.AllFlag)
+	util.PrintUtil("\nLists or prunes the result cache in the directory given by %s.\n", constants.CacheDirEnv)
+	util.PrintUtil("\nCommands:\n")
+	util.PrintUtil("  %s \tLists the cached outputs, most recently used first\n", constants.CacheListCommand)
+	util.PrintUtil("  %s \tRemoves the least recently used outputs until the cache fits its size limit\n", constants.CachePruneCommand)
+	util.PrintUtil("\nOptions:\n")
+	util.PrintUtil("  -%s \tSize in MiB to prune the cache to (default is %s or %d MiB)\n",
+		constants.MaxSizeFlag, constants.CacheSizeEnv, constants.DefaultCacheSize)
+	util.PrintUtil("  -%s \t\tRemoves every cached output\n", constants.AllFlag)
+	return
+}
+
+func readCacheEntry(dir string) (*CacheEntry, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, cacheEntryFile))
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	entry.dir = dir
+	return &entry, nil
+}
+
+func writeCacheEntry(entry *CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(entry.dir, cacheEntryFile), data, 0644)
+}
+
+//copyDir copies the files, directories and symlinks in src into dst
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+//dirSize returns the total size in bytes of the files in dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
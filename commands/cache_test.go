@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+func TestResultCacheKey(t *testing.T) {
+	base := RunRecord{ImageID: "sha256:abc", Inputs: []InputRecord{{Name: "INPUT_FILE", Path: "/data/in.tif", SHA256: "111"}},
+		Settings: map[string]string{"MODE": "fast", "TOKEN": RedactedValue}, Mounts: map[string]string{"REF": "/ref"}}
+	if result := ResultCacheKey(base, []string{"COUNT=3"}, ""); result != "" {
+		t.Errorf("ResultCacheKey with a mount == %v, expected no key without %s", result, constants.CacheMountsEnv)
+	}
+	os.Setenv(constants.CacheMountsEnv, "1")
+	defer os.Unsetenv(constants.CacheMountsEnv)
+	key := ResultCacheKey(base, []string{"COUNT=3"}, "")
+
+	cases := []struct {
+		name   string
+		modify func(r *RunRecord)
+		json   []string
+		same   bool
+	}{
+		{"identical run", func(r *RunRecord) {}, []string{"COUNT=3"}, true},
+		{"moved input file", func(r *RunRecord) {
+			r.Inputs = []InputRecord{{Name: "INPUT_FILE", Path: "/other/in.tif", SHA256: "111"}}
+		}, []string{"COUNT=3"}, true},
+		{"different secret", func(r *RunRecord) { r.Settings = map[string]string{"MODE": "fast"} }, []string{"COUNT=3"}, true},
+		{"different image", func(r *RunRecord) { r.ImageID = "sha256:def" }, []string{"COUNT=3"}, false},
+		{"different input contents", func(r *RunRecord) {
+			r.Inputs = []InputRecord{{Name: "INPUT_FILE", Path: "/data/in.tif", SHA256: "222"}}
+		}, []string{"COUNT=3"}, false},
+		{"different setting", func(r *RunRecord) { r.Settings = map[string]string{"MODE": "slow"} }, []string{"COUNT=3"}, false},
+		{"different json", func(r *RunRecord) {}, []string{"COUNT=4"}, false},
+		{"different mount", func(r *RunRecord) { r.Mounts = map[string]string{"REF": "/ref2"} }, []string{"COUNT=3"}, false},
+	}
+
+	for _, c := range cases {
+		record := base
+		c.modify(&record)
+		if result := ResultCacheKey(record, c.json, ""); (result == key) != c.same || result == "" {
+			t.Errorf("ResultCacheKey of %s == %v, expected same key as %v: %v", c.name, result, key, c.same)
+		}
+	}
+	if result := ResultCacheKey(base, []string{"COUNT=3"}, "custom-schema.json"); result == key {
+		t.Errorf("ResultCacheKey with a metadata schema == %v, expected a different key", result)
+	}
+
+	if result := ResultCacheKey(RunRecord{Image: "my-job"}, nil, ""); result != "" {
+		t.Errorf("ResultCacheKey without an image ID == %v, expected no key", result)
+	}
+	unreadable := base
+	unreadable.Inputs = []InputRecord{{Name: "INPUT_FILE", Error: "permission denied"}}
+	if result := ResultCacheKey(unreadable, nil, ""); result != "" {
+		t.Errorf("ResultCacheKey with an unreadable input == %v, expected no key", result)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cacheDir := filepath.Join(dir, "cache")
+	os.Setenv(constants.CacheSizeEnv, "1")
+	defer os.Unsetenv(constants.CacheSizeEnv)
+
+	outDir := filepath.Join(dir, "out")
+	os.MkdirAll(filepath.Join(outDir, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(outDir, "sub", "result.txt"), make([]byte, 400*1024), 0644)
+	ioutil.WriteFile(filepath.Join(outDir, constants.RunRecordFile), []byte("{}"), 0644)
+
+	record := RunRecord{Image: "my-job", ImageID: "sha256:abc"}
+	for _, key := range []string{"key1", "key2"} {
+		if err := storeCache(cacheDir, key, record, outDir); err != nil {
+			t.Fatalf("storeCache(%v) returned an error: %v", key, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entry, ok := lookupCache(cacheDir, "key1")
+	if !ok {
+		t.Fatalf("lookupCache(key1) found no entry")
+	}
+	restored := filepath.Join(dir, "restored")
+	if err := restoreCache(entry, restored); err != nil {
+		t.Fatalf("restoreCache returned an error: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(restored, "sub", "result.txt")); err != nil || info.Size() != 400*1024 {
+		t.Errorf("restoreCache did not restore sub/result.txt: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(restored, constants.RunRecordFile)); !os.IsNotExist(err) {
+		t.Errorf("restoreCache restored the run record of the cached run")
+	}
+	if _, ok := lookupCache(cacheDir, "missing"); ok {
+		t.Errorf("lookupCache(missing) found an entry")
+	}
+
+	// key1 was used last, so storing a third output evicts key2 to stay within 1 MiB
+	time.Sleep(10 * time.Millisecond)
+	if err := storeCache(cacheDir, "key3", record, outDir); err != nil {
+		t.Fatalf("storeCache(key3) returned an error: %v", err)
+	}
+	entries, err := ListCache(cacheDir)
+	if err != nil {
+		t.Fatalf("ListCache returned an error: %v", err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	if len(keys) != 2 || keys[0] != "key3" || keys[1] != "key1" {
+		t.Errorf("ListCache after eviction == %v, expected [key3 key1]", keys)
+	}
+
+	removed, err := PruneCache(cacheDir, 0)
+	if err != nil || len(removed) != 2 {
+		t.Errorf("PruneCache(0) removed %v entries with error %v, expected 2", len(removed), err)
+	}
+	if entries, _ := ListCache(cacheDir); len(entries) != 0 {
+		t.Errorf("ListCache after pruning everything == %v, expected no entries", entries)
+	}
+}
@@ -0,0 +1,279 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//OutputDirVariable is the variable job.interface.command uses for the job output directory
+const OutputDirVariable = "OUTPUT_DIR"
+
+//CommandContext holds the values of the variables job.interface.command may reference,
+// by the normalized names seed sets them as in the job's environment
+type CommandContext struct {
+	// Inputs are the file inputs: the host path of a file, or the container directory
+	// the files of a multiple input are staged in
+	Inputs map[string]string
+
+	// Json are the json inputs, read from the file given for an input if there is one
+	Json map[string]string
+
+	// Settings are the settings, including secret settings
+	Settings map[string]string
+
+	OutputDir string
+
+	// declared are the inputs and settings in the manifest, which expand to nothing when
+	// they aren't given
+	declared map[string]bool
+}
+
+//NewCommandContext returns the context to expand the job command of seed in, given the
+// KEY=VALUE file inputs, json inputs and settings of a run, the container directories
+// multiple file inputs are staged in and the job output directory. Values given for
+// names the manifest doesn't define are ignored.
+func NewCommandContext(seed *objects.Seed, inputs, json, settings []string, staged map[string]string, outputDir string) CommandContext {
+	ctx := CommandContext{Inputs: map[string]string{}, Json: map[string]string{}, Settings: map[string]string{},
+		OutputDir: outputDir, declared: map[string]bool{}}
+
+	inMap := fileInputMap(seed, inputs)
+	for _, f := range seed.Job.Interface.Inputs.Files {
+		name := util.GetNormalizedVariable(f.Name)
+		ctx.declared[name] = true
+		if values, ok := inMap[name]; ok {
+			value := util.GetFullPath(values[0], "")
+			if dir, ok := staged[name]; ok {
+				value = dir
+			}
+			ctx.Inputs[name] = value
+		}
+	}
+
+	jsonMap := inputMap(json, true)
+	for _, j := range seed.Job.Interface.Inputs.Json {
+		name := util.GetNormalizedVariable(j.Name)
+		ctx.declared[name] = true
+		if value, ok := jsonMap[name]; ok {
+			if data, err := ReadJsonFile(value); err == nil {
+				value = data
+			}
+			ctx.Json[name] = value
+		}
+	}
+
+	settingMap := inputMap(settings, true)
+	for _, s := range seed.Job.Interface.Settings {
+		name := util.GetNormalizedVariable(s.Name)
+		ctx.declared[name] = true
+		if value, ok := settingMap[name]; ok {
+			ctx.Settings[name] = value
+		}
+	}
+	return ctx
+}
+
+//Lookup returns the value of the variable name and whether it is defined. Inputs and
+// settings in the manifest that weren't given are defined with an empty value.
+func (ctx CommandContext) Lookup(name string) (string, bool) {
+	if name == OutputDirVariable {
+		return ctx.OutputDir, true
+	}
+	for _, values := range []map[string]string{ctx.Inputs, ctx.Json, ctx.Settings} {
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+	}
+	return "", ctx.declared[name]
+}
+
+//ExpandCommand splits command into arguments and expands the $NAME and ${NAME}
+// variables in it from ctx. Arguments are split on whitespace as a POSIX shell would:
+// text in single quotes is kept as is, text in double quotes is kept together with its
+// variables expanded, and a backslash escapes the character after it. Each variable is
+// expanded once, so values containing spaces, quotes or $ are passed to the job
+// unchanged as part of a single argument. An unquoted argument that expands to nothing
+// is left out. Returns an error listing the variables that aren't defined in ctx.
+func ExpandCommand(command string, ctx CommandContext) ([]string, error) {
+	words, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{}
+	undefined := map[string]bool{}
+	for _, w := range words {
+		var arg strings.Builder
+		for _, p := range w.parts {
+			if !p.variable {
+				arg.WriteString(p.text)
+				continue
+			}
+			value, ok := ctx.Lookup(p.text)
+			if !ok {
+				undefined[p.text] = true
+			}
+			arg.WriteString(value)
+		}
+		if arg.Len() > 0 || w.quoted {
+			args = append(args, arg.String())
+		}
+	}
+
+	if len(undefined) > 0 {
+		var names []string
+		for name := range undefined {
+			names = append(names, "$"+name)
+		}
+		sort.Strings(names)
+		return args, fmt.Errorf("ERROR: The job command references %s, which is not an input, setting or %s.\n"+
+			"Put it in single quotes or escape the $ with a backslash to pass it to the job unchanged.",
+			strings.Join(names, ", "), OutputDirVariable)
+	}
+	return args, nil
+}
+
+//CommandVariables returns the names of the variables command references, in the order
+// they first appear
+func CommandVariables(command string) ([]string, error) {
+	words, err := parseCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, w := range words {
+		for _, p := range w.parts {
+			if p.variable && !seen[p.text] {
+				seen[p.text] = true
+				names = append(names, p.text)
+			}
+		}
+	}
+	return names, nil
+}
+
+//commandWord is an argument of the job command: literal text and variables
+type commandWord struct {
+	parts []wordPart
+
+	// quoted is set when the word has quotes, so it is kept even if it expands to nothing
+	quoted bool
+}
+
+//wordPart is literal text, or the name of a variable if variable is set
+type wordPart struct {
+	text     string
+	variable bool
+}
+
+//parseCommand splits command into words, see ExpandCommand
+func parseCommand(command string) ([]commandWord, error) {
+	var words []commandWord
+	var word *commandWord
+	begin := func() {
+		if word == nil {
+			word = &commandWord{}
+		}
+	}
+	literal := func(text string) {
+		begin()
+		if n := len(word.parts); n > 0 && !word.parts[n-1].variable {
+			word.parts[n-1].text += text
+		} else {
+			word.parts = append(word.parts, wordPart{text: text})
+		}
+	}
+
+	inDouble := false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case c == '$':
+			name, n, err := variableName(command[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			if n == 0 {
+				literal("$")
+				continue
+			}
+			begin()
+			word.parts = append(word.parts, wordPart{text: name, variable: true})
+			i += n
+		case inDouble && c == '"':
+			inDouble = false
+		case inDouble && c == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$", command[i+1]) >= 0:
+			literal(command[i+1 : i+2])
+			i++
+		case inDouble:
+			literal(command[i : i+1])
+		case c == ' ' || c == '\t' || c == '\n':
+			if word != nil {
+				words = append(words, *word)
+				word = nil
+			}
+		case c == '"':
+			begin()
+			word.quoted = true
+			inDouble = true
+		case c == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("ERROR: Unterminated ' in the job command")
+			}
+			begin()
+			word.quoted = true
+			literal(command[i+1 : i+1+end])
+			i += end + 1
+		case c == '\\' && i+1 < len(command):
+			literal(command[i+1 : i+2])
+			i++
+		default:
+			literal(command[i : i+1])
+		}
+	}
+	if inDouble {
+		return nil, errors.New("ERROR: Unterminated \" in the job command")
+	}
+	if word != nil {
+		words = append(words, *word)
+	}
+	return words, nil
+}
+
+//variableName returns the name of the variable referenced at the start of s, the text
+// after a $, and the number of bytes of s the reference takes. Returns 0 bytes if s
+// doesn't start with a variable name or {.
+func variableName(s string) (string, int, error) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0, errors.New("ERROR: Unterminated ${ in the job command")
+		}
+		name := s[1:end]
+		if name == "" || nameLength(name) != len(name) {
+			return "", 0, fmt.Errorf("ERROR: Invalid variable ${%s} in the job command", name)
+		}
+		return name, end + 1, nil
+	}
+	n := nameLength(s)
+	return s[:n], n, nil
+}
+
+//nameLength returns the length of the variable name at the start of s: a letter or _
+// followed by letters, digits and _
+func nameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return i
+		}
+	}
+	return len(s)
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+func TestExpandCommand(t *testing.T) {
+	ctx := CommandContext{
+		Inputs:    map[string]string{"INPUT": "/in", "INPUT_FILE": "/data/my file.tif"},
+		Json:      map[string]string{"PARAMS": `{"a":"$HOME b"}`},
+		Settings:  map[string]string{"MODE": "fast $MODE"},
+		OutputDir: "/out",
+		declared:  map[string]bool{"OPTIONAL": true},
+	}
+
+	cases := []struct {
+		command  string
+		expected []string
+		errorMsg string
+	}{
+		{"", []string{}, ""},
+		{"${INPUT_FILE} ${OUTPUT_DIR}", []string{"/data/my file.tif", "/out"}, ""},
+		{"$INPUT $INPUT_FILE", []string{"/in", "/data/my file.tif"}, ""},
+		{"$INPUT_FILE.bak ${INPUT}_FILE", []string{"/data/my file.tif.bak", "/in_FILE"}, ""},
+		{"--params=$PARAMS", []string{`--params={"a":"$HOME b"}`}, ""},
+		{"-m $MODE", []string{"-m", "fast $MODE"}, ""},
+		{"sh -c 'echo $HOME \"x\"'", []string{"sh", "-c", `echo $HOME "x"`}, ""},
+		{`"two words" three`, []string{"two words", "three"}, ""},
+		{`"$OUTPUT_DIR/a b"`, []string{"/out/a b"}, ""},
+		{`a\ b cost \$5`, []string{"a b", "cost", "$5"}, ""},
+		{`"a \"quoted\" \$x \n"`, []string{`a "quoted" $x \n`}, ""},
+		{"run $OPTIONAL end", []string{"run", "end"}, ""},
+		{`run "$OPTIONAL" '' end`, []string{"run", "", "", "end"}, ""},
+		{"  run \t --x\n  ", []string{"run", "--x"}, ""},
+		{"price $5 $ $-", []string{"price", "$5", "$", "$-"}, ""},
+		{"$UNDEFINED", nil, "$UNDEFINED"},
+		{"${MISSING} $OTHER $MISSING", nil, "$MISSING, $OTHER"},
+		{"'open", nil, "Unterminated '"},
+		{`"open`, nil, `Unterminated "`},
+		{"${open", nil, "Unterminated ${"},
+		{"${1BAD}", nil, "Invalid variable ${1BAD}"},
+		{"${}", nil, "Invalid variable ${}"},
+	}
+
+	for _, c := range cases {
+		args, err := ExpandCommand(c.command, ctx)
+		if c.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errorMsg) {
+				t.Errorf("ExpandCommand(%q) returned error %v, expected %q", c.command, err, c.errorMsg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpandCommand(%q) returned an error: %v", c.command, err)
+		}
+		if !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("ExpandCommand(%q) == %q, expected %q", c.command, args, c.expected)
+		}
+	}
+}
+
+func TestCommandVariables(t *testing.T) {
+	cases := []struct {
+		command  string
+		expected []string
+	}{
+		{"run", nil},
+		{"${INPUT_FILE} $OUTPUT_DIR $INPUT_FILE", []string{"INPUT_FILE", "OUTPUT_DIR"}},
+		{"$DB_PASSWORD '$DB_PASS'", []string{"DB_PASSWORD"}},
+		{`"$A$B" \$C`, []string{"A", "B"}},
+	}
+
+	for _, c := range cases {
+		if names, err := CommandVariables(c.command); err != nil || !reflect.DeepEqual(names, c.expected) {
+			t.Errorf("CommandVariables(%q) == %q, %v, expected %q", c.command, names, err, c.expected)
+		}
+	}
+}
+
+//TestCommandFixtures expands the commands of the manifests in testdata and examples,
+// giving each file input /data/NAME, each json input NAME-json and each setting NAME-value
+func TestCommandFixtures(t *testing.T) {
+	cases := []struct {
+		manifest string
+		staged   map[string]string
+		expected []string
+		errorMsg string
+	}{
+		{"../examples/addition-job/seed.manifest.json", nil, []string{"/data/INPUT_FILE", "/out"}, ""},
+		{"../examples/extractor/seed.manifest.json", map[string]string{"MULTIPLE": "/seed-inputs/MULTIPLE"},
+			[]string{"/data/ZIP", "-d", "/out", "/seed-inputs/MULTIPLE"}, ""},
+		{"../examples/multi-addition-job/seed.manifest.json", nil, []string{"/data/INPUT_FILE", "/out"}, ""},
+		{"../guide/example/seed.manifest.json", nil,
+			[]string{"sh", "image_rotate.sh", "/data/INPUT_FILE", "DEGREES-json", "/out"}, ""},
+		{"../testdata/complete/seed.manifest.json", nil, []string{"/data/INPUT_FILE", "/out", "VERSION-value"}, ""},
+		{"../testdata/complete-denormalized/seed.manifest.json", nil,
+			[]string{"/data/INPUT_FILE", "/out", "VERSION-value"}, ""},
+		{"../testdata/multiple-required-inputs/seed.manifest.json", nil, []string{"/data/INPUT_FILE", "/out"}, ""},
+		{"../testdata/stderr-output/seed.manifest.json", nil, []string{"/data/INPUT_FILE", "/out"}, ""},
+		{"../testdata/no-inputs/seed.manifest.json", nil, nil, "references $INPUT_FILE"},
+	}
+
+	for _, c := range cases {
+		seed := objects.SeedFromManifestFile(util.GetFullPath(c.manifest, ""))
+		var inputs, json, settings []string
+		for _, f := range seed.Job.Interface.Inputs.Files {
+			inputs = append(inputs, f.Name+"=/data/"+util.GetNormalizedVariable(f.Name))
+		}
+		for _, j := range seed.Job.Interface.Inputs.Json {
+			json = append(json, j.Name+"="+util.GetNormalizedVariable(j.Name)+"-json")
+		}
+		for _, s := range seed.Job.Interface.Settings {
+			settings = append(settings, s.Name+"="+util.GetNormalizedVariable(s.Name)+"-value")
+		}
+
+		ctx := NewCommandContext(&seed, inputs, json, settings, c.staged, "/out")
+		args, err := ExpandCommand(seed.Job.Interface.Command, ctx)
+		if c.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errorMsg) {
+				t.Errorf("ExpandCommand of %s returned error %v, expected %q", c.manifest, err, c.errorMsg)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("ExpandCommand of %s == %q, %v, expected %q", c.manifest, args, err, c.expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//debugSession is an interactive shell prepared in place of a job by seed run -debug
+type debugSession struct {
+	// Args are the docker run args replacing the job's entrypoint with a shell, given
+	// before the image name
+	Args []string
+
+	// Dir is the host directory mounted at DebugScriptDir holding the job script
+	Dir string
+
+	// Banner describes the job's environment and how to run it
+	Banner string
+
+	// Script runs the job command as seed run would
+	Script string
+}
+
+//prepareDebugSession prepares a shell in the job container with the job script in
+// DebugScriptDir. runArgs are the mount, env and resource args of the job, jobArgs the
+// expanded job command and secrets the KEY=VALUE secret settings, which the script
+// reads from the container environment rather than holding their values. The script
+// directory is only created, readable by the current user alone, if create is true.
+func prepareDebugSession(imageName, containerName string, seed *objects.Seed, entrypoint, jobArgs, runArgs, secrets []string, create bool) (debugSession, error) {
+	var session debugSession
+	session.Dir = filepath.Join(os.TempDir(), containerName+"-debug")
+	if create {
+		dir, err := ioutil.TempDir("", containerName+"-debug-")
+		if err != nil {
+			return session, fmt.Errorf("ERROR: Error creating debug script directory.\n%v", err)
+		}
+		session.Dir = dir
+	}
+	script := path.Join(constants.DebugScriptDir, constants.DebugScript)
+	var quoted []string
+	for _, arg := range append(append([]string{}, entrypoint...), jobArgs...) {
+		quoted = append(quoted, scriptArg(arg, secrets))
+	}
+	command := strings.Join(quoted, " ")
+
+	// the inputs are the env vars seed sets for the job's file and json inputs
+	names := map[string]bool{}
+	for _, f := range seed.Job.Interface.Inputs.Files {
+		names[util.GetNormalizedVariable(f.Name)] = true
+	}
+	for _, j := range seed.Job.Interface.Inputs.Json {
+		names[util.GetNormalizedVariable(j.Name)] = true
+	}
+	var outputDir string
+	var inputs []string
+	for i := 0; i+1 < len(runArgs); i++ {
+		if runArgs[i] != "-e" {
+			continue
+		}
+		x := strings.SplitN(runArgs[i+1], "=", 2)
+		if x[0] == "OUTPUT_DIR" && len(x) == 2 {
+			outputDir = x[1]
+		} else if names[x[0]] {
+			inputs = append(inputs, runArgs[i+1])
+		}
+	}
+
+	var banner []string
+	banner = append(banner, "Debugging "+imageName+" in container "+containerName)
+	banner = append(banner, "OUTPUT_DIR: "+outputDir)
+	banner = append(banner, "Inputs:")
+	for _, in := range inputs {
+		banner = append(banner, "  "+in)
+	}
+	banner = append(banner, "Command:", "  "+command)
+	banner = append(banner, "Run "+script+" to run the job, sh -x "+script+" to trace it, or exit to leave the container.")
+	session.Banner = strings.Join(banner, "\n")
+
+	var lines []string
+	lines = append(lines, "#!/bin/sh", "# Runs "+imageName+" as seed run would. Inputs, settings and OUTPUT_DIR are set in the")
+	lines = append(lines, "# container environment:")
+	for _, b := range banner[1 : len(banner)-1] {
+		lines = append(lines, "# "+b)
+	}
+	lines = append(lines, command)
+	session.Script = strings.Join(lines, "\n") + "\n"
+
+	session.Args = []string{"-i"}
+	if isTerminal(os.Stdin) {
+		session.Args = append(session.Args, "-t")
+	}
+	session.Args = append(session.Args, "-v", session.Dir+":"+constants.DebugScriptDir,
+		"--entrypoint", constants.DebugShell)
+
+	if create {
+		if err := ioutil.WriteFile(filepath.Join(session.Dir, constants.DebugScript), []byte(session.Script), 0700); err != nil {
+			return session, fmt.Errorf("ERROR: Error writing debug script.\n%v", err)
+		}
+	}
+	return session, nil
+}
+
+//scriptArg shell quotes arg for the debug script, replacing the values of secrets in it
+// with references to their variables, i.e. --pass="${DB_PASS}"
+func scriptArg(arg string, secrets []string) string {
+	first, name, value := -1, "", ""
+	for _, s := range secrets {
+		x := strings.SplitN(s, "=", 2)
+		if len(x) != 2 || x[1] == "" {
+			continue
+		}
+		if i := strings.Index(arg, x[1]); i >= 0 && (first < 0 || i < first) {
+			first, name, value = i, x[0], x[1]
+		}
+	}
+	if first < 0 {
+		return shellQuote([]string{arg})
+	}
+
+	quoted := `"${` + name + `}"`
+	if first > 0 {
+		quoted = shellQuote([]string{arg[:first]}) + quoted
+	}
+	if rest := arg[first+len(value):]; rest != "" {
+		quoted += scriptArg(rest, secrets)
+	}
+	return quoted
+}
+
+//isTerminal returns whether f is a terminal, so the debug container can be given a tty
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+//runDebugShell runs the prepared debug container attached to seed's stdin, stdout
+// and stderr, returning the exit code of the shell
+func runDebugShell(command string, args []string) (int, error) {
+	shell := exec.Command(command, args...)
+	shell.Stdin = os.Stdin
+	shell.Stdout = os.Stdout
+	shell.Stderr = os.Stderr
+	err := shell.Run()
+	if exitError, ok := err.(*exec.ExitError); ok {
+		return exitError.Sys().(syscall.WaitStatus).ExitStatus(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ngageoint/seed-cli/cliutil"
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-cli/dockerapi"
+	common_const "github.com/ngageoint/seed-common/constants"
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//ManifestLabel defines the image label holding the seed manifest
+const ManifestLabel = "com.ngageoint.seed.manifest"
+
+//dockerHubAuthAddress is the server address docker uses for Docker Hub credentials
+const dockerHubAuthAddress = "https://index.docker.io/v1/"
+
+//registryAuth returns the credentials for registry, or nil if no username is given
+func registryAuth(registry, username, password string) *dockerapi.AuthConfig {
+	if username == "" {
+		return nil
+	}
+	if registry == "" {
+		registry = dockerHubAuthAddress
+	}
+	return &dockerapi.AuthConfig{Username: username, Password: password, ServerAddress: registry}
+}
+
+//imageExists checks whether the named image exists locally
+func imageExists(imageName string) (bool, error) {
+	if client := cliutil.DockerClient(); client != nil {
+		return client.ImageExists(imageName)
+	}
+	if cliutil.CurrentRuntime().Name() == constants.DockerRuntime {
+		return util.ImageExists(imageName)
+	}
+	runtimeArgs, command := cliutil.CommandArgsInit()
+	runtimeArgs = append(runtimeArgs, "image", "inspect", imageName)
+	return exec.Command(command, runtimeArgs...).Run() == nil, nil
+}
+
+//buildImage builds and tags imageName from the job directory with the seed manifest
+// set as an image label. dockerfile is the Dockerfile to build, or "." for the one in
+// the job directory. auth holds credentials for the registry base images are pulled from.
+func buildImage(imageName, jobDirectory, dockerfile, cacheFrom, seedFileName string, auth *dockerapi.AuthConfig) error {
+	contextDir := util.GetFullPath(jobDirectory, "")
+	dfile := ""
+	if dockerfile != "." && dockerfile != "" {
+		dfile = util.GetFullPath(dockerfile, "")
+	}
+
+	if client := cliutil.DockerClient(); client != nil {
+		opts := dockerapi.BuildOptions{
+			ContextDir: contextDir,
+			Dockerfile: dfile,
+			Tag:        imageName,
+			Labels:     map[string]string{ManifestLabel: objects.GetManifestLabel(seedFileName)},
+		}
+		if cacheFrom != "" {
+			opts.CacheFrom = []string{cacheFrom}
+		}
+		if auth != nil {
+			opts.Auth = map[string]dockerapi.AuthConfig{auth.ServerAddress: *auth}
+		}
+
+		util.PrintUtil("INFO: Building %s from %s with the Docker Engine API\n", imageName, contextDir)
+		if err := client.BuildImage(opts, util.StdErr); err != nil {
+			util.PrintUtil("ERROR: Error building image '%s': %s\n", imageName, err.Error())
+			return err
+		}
+		return nil
+	}
+
+	var buildArgs, dockerCommand = cliutil.CommandArgsInit()
+	buildArgs = append(buildArgs, "build")
+	// docker doesn't care about validating the cache-from image
+	if cacheFrom != "" {
+		buildArgs = append(buildArgs, "--cache-from")
+		buildArgs = append(buildArgs, cacheFrom)
+	}
+
+	buildArgs = append(buildArgs, "-t")
+	buildArgs = append(buildArgs, imageName)
+
+	if dfile != "" {
+		buildArgs = append(buildArgs, "-f")
+		buildArgs = append(buildArgs, dfile)
+	}
+
+	buildArgs = append(buildArgs, contextDir)
+
+	if cliutil.CurrentRuntime().HasLabel() {
+		// Set the seed.manifest.json contents as an image label
+		label := ManifestLabel + "=" + objects.GetManifestLabel(seedFileName)
+		buildArgs = append(buildArgs, "--label", label)
+	}
+
+	util.PrintUtil("INFO: Running %s command:\n%s %s\n", cliutil.CurrentRuntime().Name(), dockerCommand, strings.Join(buildArgs, " "))
+
+	// progress is written to stderr, so only the exit code tells us whether the build failed
+	cmd := exec.Command(dockerCommand, buildArgs...)
+	cmd.Stderr = util.StdErr
+	cmd.Stdout = util.StdErr
+	if err := cmd.Run(); err != nil {
+		util.PrintUtil("ERROR: Error executing docker build. %s\n", err.Error())
+		return err
+	}
+	return nil
+}
+
+//tagImage tags the source image with the target name
+func tagImage(source, target string) error {
+	if client := cliutil.DockerClient(); client != nil {
+		util.PrintUtil("INFO: Tagging %s as %s\n", source, target)
+		return client.TagImage(source, target)
+	}
+	if cliutil.CurrentRuntime().Name() == constants.DockerRuntime {
+		return util.Tag(source, target)
+	}
+	return runRuntime("tag", source, target)
+}
+
+//pushImage pushes the named image to its registry
+func pushImage(imageName string, auth *dockerapi.AuthConfig) error {
+	if client := cliutil.DockerClient(); client != nil {
+		util.PrintUtil("INFO: Pushing %s\n", imageName)
+		return client.PushImage(imageName, auth, util.StdErr)
+	}
+	if cliutil.CurrentRuntime().Name() == constants.DockerRuntime {
+		return util.Push(imageName)
+	}
+	return runRuntime("push", imageName)
+}
+
+//stopContainer stops the named container if it is running, and removes it if remove is true
+func stopContainer(name string, remove bool) {
+	if client := cliutil.DockerClient(); client != nil {
+		if err := client.StopContainer(name, constants.StopGracePeriod); err != nil {
+			client.KillContainer(name)
+		}
+		if remove {
+			client.RemoveContainer(name, true)
+		}
+		return
+	}
+
+	cliutil.StopContainer(name, constants.StopGracePeriod)
+	if remove {
+		cliutil.RemoveContainer(name)
+	}
+}
+
+//inspectImage returns the ID, digests, labels and entrypoint of the named image
+func inspectImage(imageName string) (*dockerapi.Image, error) {
+	if client := cliutil.DockerClient(); client != nil {
+		return client.InspectImage(imageName)
+	}
+
+	runtimeArgs, command := cliutil.CommandArgsInit()
+	runtimeArgs = append(runtimeArgs, "image", "inspect", imageName)
+	out, err := exec.Command(command, runtimeArgs...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: Error inspecting image %s. %s", imageName, err.Error())
+	}
+	var images []dockerapi.Image
+	if err := json.Unmarshal(out, &images); err != nil || len(images) == 0 {
+		return nil, fmt.Errorf("ERROR: Error reading inspect output of image %s. %v", imageName, err)
+	}
+	return &images[0], nil
+}
+
+//removeImage removes the named image
+func removeImage(imageName string) error {
+	if client := cliutil.DockerClient(); client != nil {
+		util.PrintUtil("INFO: Removing %s\n", imageName)
+		return client.RemoveImage(imageName, false)
+	}
+	if cliutil.CurrentRuntime().Name() == constants.DockerRuntime {
+		return util.RemoveImage(imageName)
+	}
+	return runRuntime("rmi", imageName)
+}
+
+//runRuntime runs the selected container runtime with the given args, writing its
+// output to stderr
+func runRuntime(args ...string) error {
+	runtimeArgs, command := cliutil.CommandArgsInit()
+	runtimeArgs = append(runtimeArgs, args...)
+	util.PrintUtil("INFO: Running %s command:\n%s %s\n", cliutil.CurrentRuntime().Name(), command, strings.Join(runtimeArgs, " "))
+
+	cmd := exec.Command(command, runtimeArgs...)
+	cmd.Stderr = util.StdErr
+	cmd.Stdout = util.StdErr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ERROR: Error executing %s %s. %s", command, strings.Join(runtimeArgs, " "), err.Error())
+	}
+	return nil
+}
+
+//registryLogin logs in to the registry with the runtime command, using a temporary
+// config directory so other users' logins aren't stomped on when running with sudo.
+// Returns a function that removes the temporary config.
+func registryLogin(registry, username, password string) (func(), error) {
+	configDir := common_const.DockerConfigDir + time.Now().Format(time.RFC3339)
+	key, value := cliutil.CurrentRuntime().AuthEnv(configDir)
+	os.Setenv(key, value)
+	cleanup := func() {
+		os.Unsetenv(key)
+		util.RemoveAllFiles(configDir)
+	}
+
+	if cliutil.CurrentRuntime().Name() == constants.DockerRuntime {
+		return cleanup, util.Login(registry, username, password)
+	}
+
+	runtimeArgs, command := cliutil.CommandArgsInit()
+	runtimeArgs = append(runtimeArgs, "login", "-u", username, "--password-stdin")
+	if registry != "" {
+		runtimeArgs = append(runtimeArgs, registry)
+	}
+	cmd := exec.Command(command, runtimeArgs...)
+	cmd.Stdin = strings.NewReader(password)
+	cmd.Stderr = util.StdErr
+	if err := cmd.Run(); err != nil {
+		return cleanup, fmt.Errorf("ERROR: Error logging in to registry %s. %s", registry, err.Error())
+	}
+	return cleanup, nil
+}
+
+//runContainer runs the container described by run through the Docker Engine API,
+// copying its output to stdout and stderr. If timeout is greater than zero and the job
+// runs longer than timeout seconds, the container is stopped. Returns the container's
+// exit code and whether it timed out.
+func runContainer(client *dockerapi.Client, run *dockerapi.RunConfig, stdout, stderr io.Writer, timeout int) (int, bool, error) {
+	id, err := client.CreateContainer(run.Name, run.Config)
+	if err != nil {
+		return -1, false, err
+	}
+	// the container is removed once its logs have been read rather than by the engine,
+	// which could remove it before we've followed its output
+	if run.AutoRemove {
+		defer client.RemoveContainer(id, true)
+	}
+
+	if err = client.StartContainer(id); err != nil {
+		return -1, false, err
+	}
+
+	logsDone := make(chan error, 1)
+	go func() {
+		logsDone <- client.ContainerLogs(id, true, stdout, stderr)
+	}()
+
+	type result struct {
+		code int
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, err := client.WaitContainer(id)
+		done <- result{code, err}
+	}()
+
+	var res result
+	timedOut := false
+	if timeout <= 0 {
+		res = <-done
+	} else {
+		select {
+		case res = <-done:
+		case <-time.After(time.Duration(timeout) * time.Second):
+			util.PrintUtil("INFO: Job timeout of %d seconds reached. Stopping container %s...\n",
+				timeout, run.Name)
+			if err := client.StopContainer(id, constants.StopGracePeriod); err != nil {
+				util.PrintUtil("ERROR: Error stopping container %s: %s\n", run.Name, err.Error())
+				client.KillContainer(id)
+			}
+			res = <-done
+			timedOut = true
+		}
+	}
+
+	if err := <-logsDone; err != nil {
+		util.PrintUtil("ERROR: Error reading output of container %s: %s\n", run.Name, err.Error())
+	}
+	if res.err != nil {
+		return -1, timedOut, res.err
+	}
+	return res.code, timedOut, nil
+}
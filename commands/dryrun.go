@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+//RunPlan is the container invocation prepared for a seed run, printed by a dry run
+type RunPlan struct {
+	Image         string `json:"image"`
+	Runtime       string `json:"runtime"`
+	ContainerName string `json:"containerName"`
+
+	// Command and Args are the runtime command line, i.e. docker run ...
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+
+	// JobCommand is job.interface.command with inputs, settings and OUTPUT_DIR substituted
+	JobCommand string `json:"jobCommand"`
+	OutputDir  string `json:"outputDir"`
+
+	// Resources are the allocated scalar resources by name, i.e. cpus and mem in MiB
+	Resources map[string]float64 `json:"resources,omitempty"`
+
+	// DiskLimit is the disk resource in MiB the output is validated against
+	DiskLimit float64 `json:"diskLimitMiB,omitempty"`
+
+	// Timeout of the job in seconds, zero if there is none
+	Timeout int `json:"timeout,omitempty"`
+}
+
+//allocatedResources returns the resource allocations from the ALLOCATED_ environment
+// variables in the resource args returned by DefineResources
+func allocatedResources(resourceArgs []string) map[string]float64 {
+	resources := map[string]float64{}
+	for i := 0; i+1 < len(resourceArgs); i++ {
+		if resourceArgs[i] != "-e" || !strings.HasPrefix(resourceArgs[i+1], "ALLOCATED_") {
+			continue
+		}
+		x := strings.SplitN(strings.TrimPrefix(resourceArgs[i+1], "ALLOCATED_"), "=", 2)
+		if len(x) != 2 {
+			continue
+		}
+		if value, err := strconv.ParseFloat(x[1], 64); err == nil {
+			resources[strings.ToLower(x[0])] = value
+		}
+	}
+	return resources
+}
+
+//PrintRunPlan prints the plan to stdout as text or json
+func PrintRunPlan(plan RunPlan, format string) error {
+	if format == constants.FormatJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+		return nil
+	}
+
+	var names []string
+	for name := range plan.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var resources []string
+	for _, name := range names {
+		resources = append(resources, name+"="+strconv.FormatFloat(plan.Resources[name], 'f', -1, 64))
+	}
+
+	fmt.Fprintf(os.Stdout, "Image:          %s\n", plan.Image)
+	fmt.Fprintf(os.Stdout, "Container:      %s\n", plan.ContainerName)
+	fmt.Fprintf(os.Stdout, "Output dir:     %s\n", plan.OutputDir)
+	fmt.Fprintf(os.Stdout, "Resources:      %s\n", strings.Join(resources, " "))
+	if plan.DiskLimit > 0 {
+		fmt.Fprintf(os.Stdout, "Disk limit:     %s MiB\n", strconv.FormatFloat(plan.DiskLimit, 'f', -1, 64))
+	}
+	if plan.Timeout > 0 {
+		fmt.Fprintf(os.Stdout, "Timeout:        %d seconds\n", plan.Timeout)
+	}
+	fmt.Fprintf(os.Stdout, "Job command:    %s\n", plan.JobCommand)
+	fmt.Fprintf(os.Stdout, "%-16s%s\n", plan.Runtime+" command:", shellQuote(append([]string{plan.Command}, plan.Args...)))
+	return nil
+}
+
+//shellQuote joins args into a command line a POSIX shell splits back into the same args
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+./:,@%") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.Replace(arg, "'", `'\''`, -1) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
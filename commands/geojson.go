@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//maxGeoJSONProblems is the number of problems reported for one GeoJSON document
+const maxGeoJSONProblems = 20
+
+//TimestampProperties are the side-car metadata properties holding ISO 8601 timestamps,
+// each pair giving the start and end of a time range
+var TimestampProperties = [][2]string{{"dataStarted", "dataEnded"}, {"sourceStarted", "sourceEnded"}}
+
+//GeoJSONProblem is a problem found in a GeoJSON document that its schema can't express
+type GeoJSONProblem struct {
+	// Path is where the problem is, i.e. features[0].geometry.coordinates[1]; empty for
+	// the document as a whole
+	Path string
+
+	Message string
+
+	// Warning is set for problems RFC 7946 says parsers shouldn't reject, such as the
+	// winding order of polygon rings
+	Warning bool
+}
+
+//String returns the problem prefixed with its path
+func (p GeoJSONProblem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+	return p.Path + ": " + p.Message
+}
+
+//CheckGeoJSON checks the GeoJSON document in data beyond what the metadata schema
+// validates: that coordinates are valid longitudes and latitudes, that polygon rings
+// are closed and wound counterclockwise, or clockwise for holes, that each bbox
+// contains the positions of its object, and that the timestamp properties of features
+// parse as ISO 8601 timestamps and end after they start. Objects of types that aren't
+// GeoJSON are ignored. Returns an error if data isn't JSON.
+func CheckGeoJSON(data []byte) ([]GeoJSONProblem, error) {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	c := &geoChecker{}
+	if o, ok := document.(map[string]interface{}); ok {
+		c.object("", o)
+	}
+	if n := len(c.problems); n > maxGeoJSONProblems {
+		// the problems left out only fail validation if one of them would have
+		more := GeoJSONProblem{Message: fmt.Sprintf("%d more problems not shown", n-maxGeoJSONProblems), Warning: true}
+		for _, p := range c.problems[maxGeoJSONProblems:] {
+			more.Warning = more.Warning && p.Warning
+		}
+		c.problems = append(c.problems[:maxGeoJSONProblems], more)
+	}
+	return c.problems, nil
+}
+
+//geoChecker collects the problems found checking a GeoJSON document
+type geoChecker struct {
+	problems []GeoJSONProblem
+}
+
+//fail records a problem that fails validation
+func (c *geoChecker) fail(path, format string, args ...interface{}) {
+	c.problems = append(c.problems, GeoJSONProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+//warn records a problem that is only a warning
+func (c *geoChecker) warn(path, format string, args ...interface{}) {
+	c.problems = append(c.problems, GeoJSONProblem{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+//object checks the GeoJSON object o at path and returns the positions in it
+func (c *geoChecker) object(path string, o map[string]interface{}) [][]float64 {
+	var positions [][]float64
+	coordinates := joinPath(path, "coordinates")
+	switch o["type"] {
+	case "FeatureCollection":
+		features, _ := o["features"].([]interface{})
+		for i, f := range features {
+			if f, ok := f.(map[string]interface{}); ok {
+				positions = append(positions, c.object(joinPath(path, "features["+strconv.Itoa(i)+"]"), f)...)
+			}
+		}
+	case "Feature":
+		if g, ok := o["geometry"].(map[string]interface{}); ok {
+			positions = c.object(joinPath(path, "geometry"), g)
+		}
+		if p, ok := o["properties"].(map[string]interface{}); ok {
+			c.properties(joinPath(path, "properties"), p)
+		}
+	case "GeometryCollection":
+		geometries, _ := o["geometries"].([]interface{})
+		for i, g := range geometries {
+			if g, ok := g.(map[string]interface{}); ok {
+				positions = append(positions, c.object(joinPath(path, "geometries["+strconv.Itoa(i)+"]"), g)...)
+			}
+		}
+	case "Point":
+		positions = c.positions(coordinates, o["coordinates"], 0)
+	case "MultiPoint", "LineString":
+		positions = c.positions(coordinates, o["coordinates"], 1)
+	case "MultiLineString":
+		positions = c.positions(coordinates, o["coordinates"], 2)
+	case "Polygon":
+		positions = c.positions(coordinates, o["coordinates"], 2)
+		c.polygon(coordinates, o["coordinates"])
+	case "MultiPolygon":
+		positions = c.positions(coordinates, o["coordinates"], 3)
+		polygons, _ := o["coordinates"].([]interface{})
+		for i, p := range polygons {
+			c.polygon(coordinates+"["+strconv.Itoa(i)+"]", p)
+		}
+	default:
+		return nil
+	}
+
+	if bbox, ok := o["bbox"]; ok {
+		c.bbox(joinPath(path, "bbox"), bbox, positions)
+	}
+	return positions
+}
+
+//positions checks the positions in v, an array nested depth deep, and returns them
+func (c *geoChecker) positions(path string, v interface{}, depth int) [][]float64 {
+	if depth > 0 {
+		var positions [][]float64
+		items, _ := v.([]interface{})
+		for i, item := range items {
+			positions = append(positions, c.positions(path+"["+strconv.Itoa(i)+"]", item, depth-1)...)
+		}
+		return positions
+	}
+
+	p, ok := position(v)
+	if !ok {
+		// malformed positions are reported by the schema
+		return nil
+	}
+	if p[0] < -180 || p[0] > 180 {
+		c.fail(path, "longitude %s is outside -180 to 180", formatFloat(p[0]))
+	}
+	if p[1] < -90 || p[1] > 90 {
+		c.fail(path, "latitude %s is outside -90 to 90", formatFloat(p[1]))
+	}
+	return [][]float64{p}
+}
+
+//polygon checks the rings of the polygon coordinates v: the first is the exterior ring
+// and the rest are holes
+func (c *geoChecker) polygon(path string, v interface{}) {
+	rings, _ := v.([]interface{})
+	for i, r := range rings {
+		items, _ := r.([]interface{})
+		var ring [][]float64
+		for _, item := range items {
+			if p, ok := position(item); ok {
+				ring = append(ring, p)
+			}
+		}
+		if len(ring) != len(items) {
+			continue
+		}
+
+		ringPath := path + "[" + strconv.Itoa(i) + "]"
+		if len(ring) < 4 {
+			c.fail(ringPath, "ring has %d positions, at least 4 are needed", len(ring))
+			continue
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if !samePosition(first, last) {
+			c.fail(ringPath, "ring isn't closed: the first position %s and last position %s differ",
+				formatPosition(first), formatPosition(last))
+			continue
+		}
+
+		area := ringArea(ring)
+		switch {
+		case area == 0:
+			c.warn(ringPath, "ring has no area")
+		case i == 0 && area < 0:
+			c.warn(ringPath, "exterior ring is clockwise, RFC 7946 expects counterclockwise")
+		case i > 0 && area > 0:
+			c.warn(ringPath, "hole is counterclockwise, RFC 7946 expects clockwise")
+		}
+	}
+}
+
+//bbox checks that v is a valid bbox containing positions
+func (c *geoChecker) bbox(path string, v interface{}, positions [][]float64) {
+	bbox, ok := position(v)
+	if !ok || (len(bbox) != 4 && len(bbox) != 6) {
+		c.fail(path, "bbox must be 4 or 6 numbers")
+		return
+	}
+
+	dims := len(bbox) / 2
+	west, south, east, north := bbox[0], bbox[1], bbox[dims], bbox[dims+1]
+	for _, lon := range []float64{west, east} {
+		if lon < -180 || lon > 180 {
+			c.fail(path, "longitude %s is outside -180 to 180", formatFloat(lon))
+			return
+		}
+	}
+	for _, lat := range []float64{south, north} {
+		if lat < -90 || lat > 90 {
+			c.fail(path, "latitude %s is outside -90 to 90", formatFloat(lat))
+			return
+		}
+	}
+	if south > north {
+		c.fail(path, "south %s is greater than north %s", formatFloat(south), formatFloat(north))
+		return
+	}
+
+	var outside [][]float64
+	for _, p := range positions {
+		in := p[1] >= south && p[1] <= north
+		if west <= east {
+			in = in && p[0] >= west && p[0] <= east
+		} else {
+			// a bbox crossing the antimeridian has west east of east
+			in = in && (p[0] >= west || p[0] <= east)
+		}
+		if dims == 3 && len(p) > 2 {
+			in = in && p[2] >= bbox[2] && p[2] <= bbox[5]
+		}
+		if !in {
+			outside = append(outside, p)
+		}
+	}
+	if len(outside) > 0 {
+		c.fail(path, "bbox %s doesn't contain %d of the %d positions of its geometry, i.e. %s",
+			formatPosition(bbox), len(outside), len(positions), formatPosition(outside[0]))
+	}
+}
+
+//properties checks the timestamp properties of a feature
+func (c *geoChecker) properties(path string, properties map[string]interface{}) {
+	for _, pair := range TimestampProperties {
+		var times [2]time.Time
+		parsed := 0
+		for i, name := range pair {
+			v, ok := properties[name]
+			if !ok || v == nil {
+				continue
+			}
+			s, _ := v.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				c.fail(joinPath(path, name), "%v isn't an ISO 8601 timestamp such as 2006-01-02T15:04:05Z", v)
+				continue
+			}
+			times[i] = t
+			parsed++
+		}
+		if parsed == 2 && times[1].Before(times[0]) {
+			c.fail(path, "%s %s is before %s %s", pair[1], times[1].Format(time.RFC3339),
+				pair[0], times[0].Format(time.RFC3339))
+		}
+	}
+}
+
+//position returns v as a GeoJSON position, an array of at least 2 numbers
+func position(v interface{}) ([]float64, bool) {
+	items, ok := v.([]interface{})
+	if !ok || len(items) < 2 {
+		return nil, false
+	}
+	p := make([]float64, len(items))
+	for i, item := range items {
+		if p[i], ok = item.(float64); !ok {
+			return nil, false
+		}
+	}
+	return p, true
+}
+
+//samePosition returns whether a and b are the same position
+func samePosition(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//ringArea returns the signed area of a closed ring by the shoelace formula: positive if
+// the ring is counterclockwise, negative if it is clockwise
+func ringArea(ring [][]float64) float64 {
+	var area float64
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area / 2
+}
+
+//joinPath returns the path of the member name of the object at path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+//formatFloat formats f without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+//formatPosition formats p as a JSON array
+func formatPosition(p []float64) string {
+	var values []string
+	for _, f := range p {
+		values = append(values, formatFloat(f))
+	}
+	return "[" + strings.Join(values, ", ") + "]"
+}
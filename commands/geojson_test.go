@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngageoint/seed-common/objects"
+)
+
+func TestCheckGeoJSON(t *testing.T) {
+	square := `[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]`
+	hole := `[[0.2, 0.2], [0.2, 0.8], [0.8, 0.8], [0.8, 0.2], [0.2, 0.2]]`
+
+	cases := []struct {
+		document string
+		errors   []string
+		warnings []string
+	}{
+		{`{"type": "Point", "coordinates": [-77.1, 38.9, 10]}`, nil, nil},
+		{`{"type": "Point", "coordinates": [190, -91]}`,
+			[]string{"coordinates: longitude 190 is outside -180 to 180", "coordinates: latitude -91 is outside -90 to 90"}, nil},
+		{`{"type": "Polygon", "coordinates": [` + square + `, ` + hole + `]}`, nil, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`,
+			[]string{"coordinates[0]: ring isn't closed: the first position [0, 0] and last position [0, 1] differ"}, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 0]]]}`,
+			[]string{"coordinates[0]: ring has 3 positions, at least 4 are needed"}, nil},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]], ` + square + `]}`, nil,
+			[]string{"coordinates[0]: exterior ring is clockwise, RFC 7946 expects counterclockwise",
+				"coordinates[1]: hole is counterclockwise, RFC 7946 expects clockwise"}},
+		{`{"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [2, 2], [0, 0]]]}`, nil,
+			[]string{"coordinates[0]: ring has no area"}},
+		{`{"type": "MultiPolygon", "coordinates": [[` + square + `], [[[5, 5], [6, 5], [6, 6], [5, 5.1]]]]}`,
+			[]string{"coordinates[1][0]: ring isn't closed: the first position [5, 5] and last position [5, 5.1] differ"}, nil},
+		{`{"type": "LineString", "coordinates": [[0, 0], [1, 1]], "bbox": [0, 0, 1, 1]}`, nil, nil},
+		{`{"type": "LineString", "coordinates": [[0, 0], [2, 1], [3, 1]], "bbox": [0, 0, 1, 1]}`,
+			[]string{"bbox: bbox [0, 0, 1, 1] doesn't contain 2 of the 3 positions of its geometry, i.e. [2, 1]"}, nil},
+		{`{"type": "LineString", "coordinates": [[179, 0], [-179, 1]], "bbox": [178, 0, -178, 1]}`, nil, nil},
+		{`{"type": "Point", "coordinates": [0, 0, 50], "bbox": [0, 0, 0, 0, 0, 10]}`,
+			[]string{"bbox: bbox [0, 0, 0, 0, 0, 10] doesn't contain 1 of the 1 positions of its geometry, i.e. [0, 0, 50]"}, nil},
+		{`{"type": "Point", "coordinates": [0, 0], "bbox": [0, 1, 0, -1]}`,
+			[]string{"bbox: south 1 is greater than north -1"}, nil},
+		{`{"type": "Point", "coordinates": [0, 0], "bbox": [0, 0, 0]}`, []string{"bbox: bbox must be 4 or 6 numbers"}, nil},
+		{`{"type": "Feature", "bbox": [0, 0, 1, 1], "geometry": {"type": "Point", "coordinates": [2, 2]},
+			"properties": {"dataStarted": "2019-03-01T12:00:00Z", "dataEnded": "2019-03-01T12:30:00.5-05:00"}}`,
+			[]string{"bbox: bbox [0, 0, 1, 1] doesn't contain 1 of the 1 positions of its geometry, i.e. [2, 2]"}, nil},
+		{`{"type": "Feature", "geometry": null,
+			"properties": {"dataStarted": "2019-03-01", "dataEnded": 5, "sourceStarted": null}}`,
+			[]string{"properties.dataStarted: 2019-03-01 isn't an ISO 8601 timestamp such as 2006-01-02T15:04:05Z",
+				"properties.dataEnded: 5 isn't an ISO 8601 timestamp such as 2006-01-02T15:04:05Z"}, nil},
+		{`{"type": "Feature", "geometry": null,
+			"properties": {"sourceStarted": "2019-03-02T00:00:00Z", "sourceEnded": "2019-03-01T00:00:00Z"}}`,
+			[]string{"properties: sourceEnded 2019-03-01T00:00:00Z is before sourceStarted 2019-03-02T00:00:00Z"}, nil},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}, "properties": {}},
+			{"type": "Feature", "properties": {},
+				"geometry": {"type": "GeometryCollection", "geometries": [{"type": "Point", "coordinates": [0, 95]}]}}]}`,
+			[]string{"features[1].geometry.geometries[0].coordinates: latitude 95 is outside -90 to 90"}, nil},
+		{`{"type": "Unknown", "coordinates": [500, 500]}`, nil, nil},
+		{`[1, 2]`, nil, nil},
+	}
+
+	for _, c := range cases {
+		problems, err := CheckGeoJSON([]byte(c.document))
+		if err != nil {
+			t.Errorf("CheckGeoJSON(%s) returned an error: %v", c.document, err)
+			continue
+		}
+		var errs, warnings []string
+		for _, p := range problems {
+			if p.Warning {
+				warnings = append(warnings, p.String())
+			} else {
+				errs = append(errs, p.String())
+			}
+		}
+		if !reflect.DeepEqual(errs, c.errors) || !reflect.DeepEqual(warnings, c.warnings) {
+			t.Errorf("CheckGeoJSON(%s) == %q and warnings %q, expected %q and warnings %q",
+				c.document, errs, warnings, c.errors, c.warnings)
+		}
+	}
+
+	if _, err := CheckGeoJSON([]byte("{")); err == nil {
+		t.Errorf("CheckGeoJSON of invalid JSON returned no error")
+	}
+
+	// a document with many problems reports the first of them
+	var points []string
+	for i := 0; i < 25; i++ {
+		points = append(points, "[200, 0]")
+	}
+	problems, _ := CheckGeoJSON([]byte(`{"type": "MultiPoint", "coordinates": [` + strings.Join(points, ", ") + `]}`))
+	if len(problems) != maxGeoJSONProblems+1 || problems[maxGeoJSONProblems].String() != "5 more problems not shown" ||
+		problems[maxGeoJSONProblems].Warning {
+		t.Errorf("CheckGeoJSON of 25 problems returned %v problems, expected %v and a count of the rest",
+			len(problems), maxGeoJSONProblems+1)
+	}
+}
+
+func TestCheckRunOutputGeoJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-outputs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a.txt.metadata.json"), []byte(`{"type": "Feature", "properties": {},
+		"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [0, 1], [1, 1], [1, 0]]]}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.txt.metadata.json"), []byte(`{"type": "Feature", "properties": {},
+		"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [0, 1], [1, 1], [1, 0], [0, 0]]]}}`), 0644)
+
+	seed := &objects.Seed{}
+	seed.Job.Interface.Outputs.Files = []objects.OutFile{{Name: "TEXT", MediaType: "text/plain", Pattern: "*.txt", Multiple: true}}
+	result, _ := CheckRunOutput(seed, dir, "", 0)
+
+	// the schema check may also report errors, so only the GeoJSON problems are compared
+	var unclosed []MetadataError
+	for _, m := range result.Files[0].MetadataErrors {
+		if strings.Contains(m.Error, "isn't closed") {
+			unclosed = append(unclosed, m)
+		}
+	}
+	if len(unclosed) != 1 || unclosed[0].File != filepath.Join(dir, "a.txt.metadata.json") {
+		t.Errorf("CheckRunOutput metadata errors == %v, expected the unclosed ring in a.txt.metadata.json", result.Files[0].MetadataErrors)
+	}
+	warnings := result.Files[0].MetadataWarnings
+	if len(warnings) != 1 || warnings[0].File != filepath.Join(dir, "b.txt.metadata.json") ||
+		!strings.Contains(warnings[0].Error, "clockwise") {
+		t.Errorf("CheckRunOutput metadata warnings == %v, expected the clockwise ring in b.txt.metadata.json", warnings)
+	}
+	if result.Valid() {
+		t.Errorf("CheckRunOutput with an unclosed ring in a side-car metadata file is valid")
+	}
+}
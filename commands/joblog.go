@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+//logTimestampFormat is the fixed width timestamp written at the start of each line of
+// the job logs when timestamps are enabled
+const logTimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+//jobLogs are the stdout.log and stderr.log files a job's output is tee'd to in its output directory
+type jobLogs struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	redactors []*redactWriter
+	files     []*os.File
+}
+
+//openJobLogs creates stdout.log and stderr.log in outDir. The values of secrets, given
+// as KEY=VALUE pairs, are redacted from the logs. If timestamps is true each line
+// written to them is prefixed with the time it was written.
+func openJobLogs(outDir string, timestamps bool, secrets []string) (*jobLogs, error) {
+	logs := &jobLogs{}
+	for _, name := range []string{constants.StdoutLogFile, constants.StderrLogFile} {
+		file, err := os.Create(filepath.Join(outDir, name))
+		if err != nil {
+			logs.Close()
+			return nil, err
+		}
+		logs.files = append(logs.files, file)
+		var w io.Writer = file
+		if timestamps {
+			w = &timestampWriter{w: file}
+		}
+		logs.redactors = append(logs.redactors, newRedactWriter(w, secrets))
+	}
+	logs.Stdout, logs.Stderr = logs.redactors[0], logs.redactors[1]
+	return logs, nil
+}
+
+//Close writes any output held back for redaction and closes the log files. Closing
+// the logs again does nothing.
+func (l *jobLogs) Close() error {
+	var err error
+	for _, r := range l.redactors {
+		if flushErr := r.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+	}
+	for _, f := range l.files {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	l.redactors, l.files = nil, nil
+	return err
+}
+
+//timestampWriter prefixes each line written through it with the current time
+type timestampWriter struct {
+	w       io.Writer
+	midLine bool
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+func (t *timestampWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if !t.midLine {
+			now := time.Now()
+			if t.now != nil {
+				now = t.now()
+			}
+			if _, err := io.WriteString(t.w, now.Format(logTimestampFormat)+" "); err != nil {
+				return n - len(p), err
+			}
+		}
+		line := p
+		if i := bytes.IndexByte(p, '\n'); i >= 0 {
+			line = p[:i+1]
+		}
+		if _, err := t.w.Write(line); err != nil {
+			return n - len(p), err
+		}
+		t.midLine = line[len(line)-1] != '\n'
+		p = p[len(line):]
+	}
+	return n, nil
+}
+
+//tailBuffer keeps the last max bytes written to it, so a job writing a lot to stderr
+// isn't buffered in memory in full
+type tailBuffer struct {
+	max     int
+	buf     []byte
+	dropped int64
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) >= b.max {
+		b.dropped += int64(len(b.buf) + len(p) - b.max)
+		b.buf = append(b.buf[:0], p[len(p)-b.max:]...)
+		return n, nil
+	}
+	if over := len(b.buf) + len(p) - b.max; over > 0 {
+		copy(b.buf, b.buf[over:])
+		b.buf = b.buf[:len(b.buf)-over]
+		b.dropped += int64(over)
+	}
+	b.buf = append(b.buf, p...)
+	return n, nil
+}
+
+//Dropped returns the number of bytes written to the buffer that are no longer kept
+func (b *tailBuffer) Dropped() int64 {
+	return b.dropped
+}
+
+//String returns the kept bytes. Once bytes have been dropped it starts at the first
+// whole line kept rather than partway through one.
+func (b *tailBuffer) String() string {
+	data := b.buf
+	if b.dropped > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return string(data)
+}
+
+//Summary returns the kept bytes, noting when earlier output was dropped and where the
+// full output can be found if logFile isn't empty
+func (b *tailBuffer) Summary(logFile string) string {
+	if b.dropped == 0 {
+		return b.String()
+	}
+	dropped := b.dropped + int64(len(b.buf)-len(b.String()))
+	note := fmt.Sprintf("... %d earlier bytes omitted", dropped)
+	if logFile != "" {
+		note += "; see " + logFile + " for the full output"
+	}
+	return note + " ...\n" + b.String()
+}
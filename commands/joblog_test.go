@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+func TestTimestampWriter(t *testing.T) {
+	now := time.Date(2019, 3, 4, 5, 6, 7, 8000, time.UTC)
+	stamp := now.Format(logTimestampFormat) + " "
+
+	cases := []struct {
+		writes   []string
+		expected string
+	}{
+		{[]string{}, ""},
+		{[]string{"one\n"}, stamp + "one\n"},
+		{[]string{"one\ntwo\n"}, stamp + "one\n" + stamp + "two\n"},
+		{[]string{"par", "tial\n"}, stamp + "partial\n"},
+		{[]string{"one\ntw", "o\nthree"}, stamp + "one\n" + stamp + "two\n" + stamp + "three"},
+		{[]string{"\n\n"}, stamp + "\n" + stamp + "\n"},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+		w := &timestampWriter{w: &out, now: func() time.Time { return now }}
+		for _, s := range c.writes {
+			if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("Write(%q) == %v, %v, expected %v, nil", s, n, err, len(s))
+			}
+		}
+		if out.String() != c.expected {
+			t.Errorf("timestampWriter after writing %q == %q, expected %q", c.writes, out.String(), c.expected)
+		}
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	cases := []struct {
+		writes   []string
+		expected string
+		dropped  int64
+	}{
+		{[]string{"abc"}, "abc", 0},
+		{[]string{"abc", "defghij"}, "abcdefghij", 0},
+		{[]string{"one\n", "two\n", "three\n"}, "three\n", 4},
+		{[]string{"one\ntwo\nthree\n"}, "three\n", 4},
+		{[]string{"0123456789abc"}, "3456789abc", 3},
+		{[]string{"1\n", "0123456789\n"}, "", 3},
+	}
+
+	for _, c := range cases {
+		b := newTailBuffer(10)
+		for _, s := range c.writes {
+			if n, err := b.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("Write(%q) == %v, %v, expected %v, nil", s, n, err, len(s))
+			}
+		}
+		if b.String() != c.expected || b.Dropped() != c.dropped {
+			t.Errorf("tailBuffer after writing %q == %q with %v dropped, expected %q with %v dropped",
+				c.writes, b.String(), b.Dropped(), c.expected, c.dropped)
+		}
+		if len(b.buf) > 10 {
+			t.Errorf("tailBuffer after writing %q kept %v bytes, expected at most 10", c.writes, len(b.buf))
+		}
+	}
+
+	b := newTailBuffer(10)
+	b.Write([]byte("one\ntwo\nthree\n"))
+	if summary := b.Summary("out/stderr.log"); summary != "... 8 earlier bytes omitted; see out/stderr.log for the full output ...\nthree\n" {
+		t.Errorf("Summary of a truncated buffer == %q", summary)
+	}
+	b = newTailBuffer(10)
+	b.Write([]byte("short\n"))
+	if summary := b.Summary("out/stderr.log"); summary != "short\n" {
+		t.Errorf("Summary of a buffer under its limit == %q, expected %q", summary, "short\n")
+	}
+}
+
+func TestOpenJobLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-logs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, timestamps := range []bool{false, true} {
+		logs, err := openJobLogs(dir, timestamps, nil)
+		if err != nil {
+			t.Fatalf("openJobLogs returned an error: %v", err)
+		}
+		logs.Stdout.Write([]byte("out\n"))
+		logs.Stderr.Write([]byte("err\n"))
+		if err := logs.Close(); err != nil {
+			t.Errorf("Close returned an error: %v", err)
+		}
+
+		for name, expected := range map[string]string{constants.StdoutLogFile: "out\n", constants.StderrLogFile: "err\n"} {
+			data, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Errorf("openJobLogs did not create %s: %v", name, err)
+				continue
+			}
+			if timestamps {
+				if _, err := time.Parse(logTimestampFormat, strings.SplitN(string(data), " ", 2)[0]); err != nil ||
+					!strings.HasSuffix(string(data), " "+expected) {
+					t.Errorf("%s with timestamps == %q, expected a timestamp and %q", name, data, expected)
+				}
+			} else if string(data) != expected {
+				t.Errorf("%s == %q, expected %q", name, data, expected)
+			}
+		}
+	}
+}
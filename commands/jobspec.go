@@ -0,0 +1,298 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v2"
+)
+
+//JobSpec describes a seed run or batch in a YAML or JSON file given with -f. Values
+// given with flags override the values in the file.
+type JobSpec struct {
+	Image    string `yaml:"image"`
+	Manifest string `yaml:"manifest"`
+
+	// Inputs maps file input names to a path, or a list of paths for a multiple input
+	Inputs map[string]stringList `yaml:"inputs"`
+
+	// Json maps JSON input names to a value, or to the path of a file holding the value
+	Json map[string]interface{} `yaml:"json"`
+
+	// Settings are kept as written, so a version of 1.0 is not passed as 1
+	Settings  map[string]string `yaml:"settings"`
+	Mounts    map[string]string `yaml:"mounts"`
+	OutputDir string            `yaml:"outputDir"`
+	Schema    string            `yaml:"schema"`
+	Repeat    int               `yaml:"repeat"`
+
+	// Resources maps scalar resource names to overrides, i.e. cpus: 2 or mem: max:1024
+	Resources map[string]string `yaml:"resources"`
+
+	// Batch and Directory give the rows of a seed batch
+	Batch     string `yaml:"batch"`
+	Directory string `yaml:"directory"`
+
+	// document is the file as parsed, validated by Validate
+	document interface{}
+}
+
+//stringList is a list of strings that may be written in a job spec file as a single string
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []string
+	if err := unmarshal(&list); err == nil {
+		*l = list
+		return nil
+	}
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	*l = stringList{s}
+	return nil
+}
+
+//LoadJobSpec reads a job spec from a YAML or JSON file. Relative paths in the file are
+// relative to the directory containing it.
+func LoadJobSpec(path string) (JobSpec, error) {
+	var spec JobSpec
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return spec, fmt.Errorf("ERROR: Error reading job spec file %s.\n%v", path, err)
+	}
+
+	// YAML is a superset of JSON so both are read the same way
+	if err = yaml.Unmarshal(data, &spec.document); err != nil {
+		return spec, fmt.Errorf("ERROR: Error parsing job spec file %s.\n%v", path, err)
+	}
+	spec.document = jsonValue(spec.document)
+	if err = yaml.Unmarshal(data, &spec); err != nil {
+		return spec, fmt.Errorf("ERROR: Error parsing job spec file %s.\n%v", path, err)
+	}
+
+	dir := filepath.Dir(util.GetFullPath(path, ""))
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	spec.Manifest = resolve(spec.Manifest)
+	spec.OutputDir = resolve(spec.OutputDir)
+	spec.Schema = resolve(spec.Schema)
+	spec.Batch = resolve(spec.Batch)
+	spec.Directory = resolve(spec.Directory)
+	for _, paths := range spec.Inputs {
+		for i, p := range paths {
+			paths[i] = resolve(p)
+		}
+	}
+	for k, v := range spec.Mounts {
+		spec.Mounts[k] = resolve(v)
+	}
+	for k, v := range spec.Json {
+		// string values naming a file next to the spec are read from that file
+		if s, ok := v.(string); ok {
+			if _, err := os.Stat(resolve(s)); err == nil {
+				spec.Json[k] = resolve(s)
+			}
+		}
+	}
+	return spec, nil
+}
+
+//InputArgs returns the spec's file inputs as KEY=VALUE arguments, one for each path of
+// a multiple input
+func (spec JobSpec) InputArgs() []string {
+	var args []string
+	for k, paths := range spec.Inputs {
+		for _, p := range paths {
+			args = append(args, k+"="+p)
+		}
+	}
+	sort.Strings(args)
+	return args
+}
+
+//JsonArgs returns the spec's JSON inputs as KEY=VALUE arguments. Values other than
+// strings are passed as JSON.
+func (spec JobSpec) JsonArgs() []string {
+	values := map[string]string{}
+	for k, v := range spec.Json {
+		if s, ok := v.(string); ok {
+			values[k] = s
+		} else {
+			data, _ := json.Marshal(jsonValue(v))
+			values[k] = string(data)
+		}
+	}
+	return keyValueArgs(values)
+}
+
+//SettingArgs returns the spec's settings as KEY=VALUE arguments
+func (spec JobSpec) SettingArgs() []string {
+	return keyValueArgs(spec.Settings)
+}
+
+//MountArgs returns the spec's mounts as KEY=VALUE arguments
+func (spec JobSpec) MountArgs() []string {
+	return keyValueArgs(spec.Mounts)
+}
+
+//ResourceArgs returns the spec's resource overrides as NAME=VALUE arguments
+func (spec JobSpec) ResourceArgs() []string {
+	return keyValueArgs(spec.Resources)
+}
+
+//keyValueArgs returns KEY=VALUE arguments sorted by key
+func keyValueArgs(values map[string]string) []string {
+	var args []string
+	for k, v := range values {
+		args = append(args, k+"="+v)
+	}
+	sort.Strings(args)
+	return args
+}
+
+//jsonValue converts the maps decoded from YAML, which may have keys of any type, to
+// maps with string keys that can be encoded as JSON
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			m[fmt.Sprint(k)] = jsonValue(val)
+		}
+		return m
+	case []interface{}:
+		for i := range t {
+			t[i] = jsonValue(t[i])
+		}
+	}
+	return v
+}
+
+//Validate checks the job spec file against a schema generated from the seed manifest:
+// inputs, JSON inputs, settings and mounts must be defined by the manifest and JSON
+// input values must be of the type it declares. Inputs may be missing from the file as
+// they can be given with flags. A batch spec has no inputs.
+func (spec JobSpec) Validate(seed *objects.Seed, batch bool) error {
+	schema, err := json.Marshal(JobSpecSchema(seed, batch))
+	if err != nil {
+		return err
+	}
+
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(spec.document))
+	if err != nil {
+		return fmt.Errorf("ERROR: Error validating job spec file.\n%v", err)
+	}
+	if !result.Valid() {
+		var errs []string
+		for _, desc := range result.Errors() {
+			errs = append(errs, "  "+desc.String())
+		}
+		return fmt.Errorf("ERROR: Job spec file is invalid:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+//JobSpecSchema returns the JSON schema of a job spec file for the seed manifest
+func JobSpecSchema(seed *objects.Seed, batch bool) map[string]interface{} {
+	str := map[string]interface{}{"type": "string"}
+	scalar := map[string]interface{}{"type": []string{"string", "number", "integer", "boolean"}}
+
+	// names are accepted as written in the manifest or normalized as they are for flags
+	object := func(names []string, value func(i int) interface{}) map[string]interface{} {
+		properties := map[string]interface{}{}
+		for i, name := range names {
+			properties[name] = value(i)
+			properties[util.GetNormalizedVariable(name)] = value(i)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+
+	var settings, mounts []string
+	for _, s := range seed.Job.Interface.Settings {
+		settings = append(settings, s.Name)
+	}
+	for _, m := range seed.Job.Interface.Mounts {
+		mounts = append(mounts, m.Name)
+	}
+
+	properties := map[string]interface{}{
+		"image":     str,
+		"manifest":  str,
+		"settings":  object(settings, func(int) interface{} { return scalar }),
+		"mounts":    object(mounts, func(int) interface{} { return str }),
+		"outputDir": str,
+		"schema":    str,
+		"resources": map[string]interface{}{"type": "object", "additionalProperties": scalar},
+	}
+
+	if batch {
+		properties["batch"] = str
+		properties["directory"] = str
+	} else {
+		var inputs, jsonInputs []string
+		for _, f := range seed.Job.Interface.Inputs.Files {
+			inputs = append(inputs, f.Name)
+		}
+		for _, j := range seed.Job.Interface.Inputs.Json {
+			jsonInputs = append(jsonInputs, j.Name)
+		}
+		properties["inputs"] = object(inputs, func(i int) interface{} {
+			// a multiple input may be given a list of paths
+			if seed.Job.Interface.Inputs.Files[i].Multiple {
+				return map[string]interface{}{"anyOf": []interface{}{
+					str, map[string]interface{}{"type": "array", "items": str, "minItems": 1}}}
+			}
+			return str
+		})
+		properties["json"] = object(jsonInputs, func(i int) interface{} {
+			// a string may also be the path of a file holding the value
+			return map[string]interface{}{"anyOf": []interface{}{
+				map[string]interface{}{"type": seed.Job.Interface.Inputs.Json[i].Type}, str}}
+		})
+		properties["repeat"] = map[string]interface{}{"type": "integer", "minimum": 1}
+	}
+
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-04/schema#",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+//MergeArgs returns the KEY=VALUE arguments from a job spec file followed by those given
+// with flags, dropping any file argument whose key was also given with a flag
+func MergeArgs(fileArgs, flagArgs []string) []string {
+	given := map[string]bool{}
+	var args []string
+	for _, arg := range flagArgs {
+		if arg != "" {
+			given[util.GetNormalizedVariable(strings.SplitN(arg, "=", 2)[0])] = true
+		}
+	}
+	for _, arg := range fileArgs {
+		if !given[util.GetNormalizedVariable(strings.SplitN(arg, "=", 2)[0])] {
+			args = append(args, arg)
+		}
+	}
+	for _, arg := range flagArgs {
+		if arg != "" {
+			args = append(args, arg)
+		}
+	}
+	return args
+}
@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Batch row states recorded in the batch journal
+const (
+	RowPending     = "pending"
+	RowRunning     = "running"
+	RowSucceeded   = "succeeded"
+	RowFailed      = "failed"
+	RowInterrupted = "interrupted"
+)
+
+//JournalRow records the state of one row of a batch
+type JournalRow struct {
+	Inputs   []string `json:"inputs"`
+	Json     []string `json:"json,omitempty"`
+	Settings []string `json:"settings,omitempty"`
+	Outdir   string   `json:"outdir"`
+	Status   string   `json:"status"`
+	ExitCode int      `json:"exitCode"`
+	TimedOut bool     `json:"timedOut,omitempty"`
+	Attempts int      `json:"attempts"`
+	Duration float64  `json:"durationSeconds"`
+	Error    string   `json:"error,omitempty"`
+
+	// JobError is the job.errors entry matching the exit code of a failed run
+	JobError *JobError `json:"jobError,omitempty"`
+
+	// Validation lists the problems found validating the run's output
+	Validation []string `json:"validation,omitempty"`
+}
+
+//BatchJournal is the persisted state of a batch, rewritten each time a row changes
+// state so an interrupted batch can be resumed
+type BatchJournal struct {
+	Image   string       `json:"image"`
+	Updated time.Time    `json:"updated"`
+	Rows    []JournalRow `json:"rows"`
+
+	path  string
+	mutex sync.Mutex
+}
+
+//NewBatchJournal returns a journal at path with a pending row for each batch input.
+// Rows that succeeded in the previous journal, if given, are carried over as succeeded.
+func NewBatchJournal(path, imageName string, inputs []BatchIO, previous *BatchJournal) *BatchJournal {
+	succeeded := map[string]JournalRow{}
+	if previous != nil {
+		for _, row := range previous.Rows {
+			if row.Status == RowSucceeded {
+				succeeded[rowKey(row.Inputs, row.Json, row.Settings)] = row
+			}
+		}
+	}
+
+	journal := &BatchJournal{Image: imageName, path: path}
+	for _, in := range inputs {
+		if row, ok := succeeded[rowKey(in.Inputs, in.Json, in.Settings)]; ok {
+			journal.Rows = append(journal.Rows, row)
+			continue
+		}
+		journal.Rows = append(journal.Rows, JournalRow{Inputs: in.Inputs, Json: in.Json, Settings: in.Settings,
+			Outdir: in.Outdir, Status: RowPending})
+	}
+	return journal
+}
+
+//LoadBatchJournal reads the journal at path. Returns nil if there is no journal.
+func LoadBatchJournal(path string) (*BatchJournal, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	journal := &BatchJournal{path: path}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, err
+	}
+	return journal, nil
+}
+
+//Update records the new state of row i and saves the journal
+func (j *BatchJournal) Update(i int, update func(row *JournalRow)) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	update(&j.Rows[i])
+	return j.save()
+}
+
+//Interrupt records rows that are still running as interrupted and saves the journal
+func (j *BatchJournal) Interrupt() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	for i := range j.Rows {
+		if j.Rows[i].Status == RowRunning {
+			j.Rows[i].Status = RowInterrupted
+			j.Rows[i].Error = "Interrupted"
+		}
+	}
+	return j.save()
+}
+
+//Save writes the journal to disk
+func (j *BatchJournal) Save() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.save()
+}
+
+//save writes the journal to a temporary file and renames it so an interrupted write
+// never leaves a truncated journal behind
+func (j *BatchJournal) save() error {
+	j.Updated = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(j.path), filepath.Base(j.path)+".")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), j.path)
+}
+
+//rowKey identifies a batch row by its inputs and settings
+func rowKey(inputs, json, settings []string) string {
+	return strings.Join(inputs, "\x00") + "\x01" + strings.Join(json, "\x00") + "\x01" + strings.Join(settings, "\x00")
+}
+
+//retryBackoff returns how long to wait before retrying a failed row for the given
+// retry attempt, starting at one second and doubling up to a minute
+func retryBackoff(attempt int) time.Duration {
+	backoff := time.Second
+	for i := 1; i < attempt && backoff < time.Minute; i++ {
+		backoff *= 2
+	}
+	if backoff > time.Minute {
+		backoff = time.Minute
+	}
+	return backoff
+}
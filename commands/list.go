@@ -16,8 +16,8 @@ import (
 func DockerList() (string, error) {
 	var errs, out bytes.Buffer
 	var cmd *exec.Cmd
-	reference := util.DockerVersionHasReferenceFilter()
-	var buildArgs, dockerCommand = cliutil.DockerCommandArgsInit()
+	reference := cliutil.CurrentRuntime().HasReferenceFilter()
+	var buildArgs, dockerCommand = cliutil.CommandArgsInit()
 	if reference {
 		buildArgs = append(buildArgs, "images", "--filter=reference=*-seed*")
 		cmd = exec.Command(dockerCommand, buildArgs...)
@@ -51,8 +51,8 @@ func DockerList() (string, error) {
 	// run images
 	err := cmd.Run()
 	if reference && err != nil {
-		util.PrintUtil("ERROR: Error executing docker images.\n%s\n",
-			err.Error())
+		util.PrintUtil("ERROR: Error executing %s images.\n%s\n",
+			cliutil.CurrentRuntime().Name(), err.Error())
 		return "", err
 	}
 
@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//MediaTypeMapping identifies a media type that content sniffing and the system mime
+// table don't know about by its file extensions and content
+type MediaTypeMapping struct {
+	MediaType  string
+	Extensions []string
+
+	// Match returns whether the file starting with header is of this media type. The
+	// whole file can be read from r if the header isn't enough.
+	Match func(r io.ReaderAt, header []byte) bool
+}
+
+//mediaTypeRegistry is checked in order before content sniffing, so more specific
+// types such as GeoTIFF must be registered before the types they refine
+var mediaTypeRegistry = []MediaTypeMapping{
+	{MediaType: "application/x-hdf5", Extensions: []string{".h5", ".hdf5", ".he5"},
+		Match: hasPrefix([]byte("\x89HDF\r\n\x1a\n"))},
+	{MediaType: "application/x-hdf", Extensions: []string{".hdf", ".h4", ".hdf4"},
+		Match: hasPrefix([]byte("\x0e\x03\x13\x01"))},
+	{MediaType: "image/tiff; application=geotiff", Extensions: []string{".gtif", ".gtiff"},
+		Match: isGeoTIFF},
+	{MediaType: "image/tiff", Extensions: []string{".tif", ".tiff"},
+		Match: hasPrefix([]byte("II*\x00"), []byte("MM\x00*"))},
+	{MediaType: "application/vnd.nitf", Extensions: []string{".ntf", ".nitf", ".nsf"},
+		Match: hasPrefix([]byte("NITF"), []byte("NSIF"))},
+}
+
+//RegisterMediaType adds a media type mapping, checked before the built in mappings
+func RegisterMediaType(mapping MediaTypeMapping) {
+	mediaTypeRegistry = append([]MediaTypeMapping{mapping}, mediaTypeRegistry...)
+}
+
+func hasPrefix(prefixes ...[]byte) func(io.ReaderAt, []byte) bool {
+	return func(r io.ReaderAt, header []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(header, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+//geoKeyDirectoryTag is the TIFF tag present in every GeoTIFF
+const geoKeyDirectoryTag = 34735
+
+//isGeoTIFF returns whether the first image directory of a TIFF file has a GeoKeyDirectory tag
+func isGeoTIFF(r io.ReaderAt, header []byte) bool {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(header, []byte("II*\x00")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(header, []byte("MM\x00*")):
+		order = binary.BigEndian
+	default:
+		return false
+	}
+
+	buf := make([]byte, 12)
+	if _, err := r.ReadAt(buf[:4], 4); err != nil {
+		return false
+	}
+	offset := int64(order.Uint32(buf[:4]))
+	if _, err := r.ReadAt(buf[:2], offset); err != nil {
+		return false
+	}
+	entries := int64(order.Uint16(buf[:2]))
+	for i := int64(0); i < entries; i++ {
+		if _, err := r.ReadAt(buf, offset+2+i*12); err != nil {
+			return false
+		}
+		if order.Uint16(buf[:2]) == geoKeyDirectoryTag {
+			return true
+		}
+	}
+	return false
+}
+
+//DetectMediaType returns the media type of the file at path. Registered mappings are
+// checked first, then the content is sniffed. Content that sniffs as plain text or
+// unknown binary is typed by its extension instead if the extension is known.
+func DetectMediaType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	for _, m := range mediaTypeRegistry {
+		if m.Match != nil && m.Match(f, header) {
+			return m.MediaType, nil
+		}
+	}
+
+	sniffed := http.DetectContentType(header)
+	if !strings.HasPrefix(sniffed, "text/plain") && sniffed != constants.UnknownMediaType {
+		return sniffed, nil
+	}
+
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, m := range mediaTypeRegistry {
+		for _, e := range m.Extensions {
+			if e == ext {
+				return m.MediaType, nil
+			}
+		}
+	}
+	if byExt := mime.TypeByExtension(ext); byExt != "" {
+		return byExt, nil
+	}
+	return sniffed, nil
+}
+
+//MediaTypeMatches returns whether the detected media type is one of the accepted
+// media types. An accepted type without parameters matches any parameters of the
+// detected type and a type of the form image/* matches any subtype. An empty list or
+// application/octet-stream accepts anything.
+func MediaTypeMatches(detected string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	dType, dParams, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range accepted {
+		aType, aParams, err := mime.ParseMediaType(a)
+		if err != nil {
+			continue
+		}
+		if aType == constants.UnknownMediaType {
+			return true
+		}
+		if aType != dType && !(strings.HasSuffix(aType, "/*") && strings.HasPrefix(dType, strings.TrimSuffix(aType, "*"))) {
+			continue
+		}
+		match := true
+		for k, v := range aParams {
+			if k != "charset" && !strings.EqualFold(dParams[k], v) {
+				match = false
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+//CheckInputMediaTypes compares the media type of each input file, or each file in an
+// input directory and its subdirectories, to the mediaTypes in the seed manifest. With
+// the warn policy any mismatches are printed as warnings; with the fail policy they are
+// returned as an error. Files whose type can't be determined are not treated as
+// mismatches. filter selects the files of directories given for multiple inputs, as
+// when they are staged.
+func CheckInputMediaTypes(seed *objects.Seed, inputs []string, filter InputFilter, policy string) error {
+	if policy == constants.MediaTypeOff {
+		return nil
+	}
+
+	inMap := fileInputMap(seed, inputs)
+	var mismatches []string
+	for _, f := range seed.Job.Interface.Inputs.Files {
+		values, ok := inMap[util.GetNormalizedVariable(f.Name)]
+		if !ok || len(f.MediaTypes) == 0 {
+			continue
+		}
+
+		paths := []string{util.GetFullPath(values[0], "")}
+		if f.Multiple {
+			// missing files are reported when the input is staged
+			paths, _ = resolveInputPaths(values, filter)
+		}
+		// directories left unexpanded are mounted whole, so every file in them is checked
+		var files []string
+		for _, path := range paths {
+			filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+				if err == nil && info.Mode().IsRegular() {
+					files = append(files, file)
+				}
+				return nil
+			})
+		}
+
+		for _, file := range files {
+			detected, err := DetectMediaType(file)
+			if err != nil || detected == constants.UnknownMediaType {
+				continue
+			}
+			if !MediaTypeMatches(detected, f.MediaTypes) {
+				mismatches = append(mismatches, "Input "+f.Name+" file "+file+" has media type "+detected+
+					", expected one of "+strings.Join(f.MediaTypes, ", "))
+			}
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+	if policy == constants.MediaTypeFail {
+		return errors.New("ERROR: " + strings.Join(mismatches, "\nERROR: ") + "\n")
+	}
+	for _, m := range mismatches {
+		util.PrintUtil("WARNING: %s\n", m)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/objects"
+)
+
+//OutputValidation is the result of validating a job's output directory against the
+// outputs defined in its seed manifest
+type OutputValidation struct {
+	// DiskUsage is the size of the output directory in MiB
+	DiskUsage float64 `json:"diskUsageMiB"`
+
+	// DiskLimit is the disk resource of the job in MiB, zero if unlimited
+	DiskLimit float64 `json:"diskLimitMiB,omitempty"`
+
+	Files []OutputFileResult `json:"files,omitempty"`
+
+	// JSON lists the problems found validating seed.outputs.json
+	JSON []OutputJSONError `json:"json,omitempty"`
+}
+
+//OutputFileResult is the result of validating one outputs.files entry
+type OutputFileResult struct {
+	Name     string `json:"name"`
+	Pattern  string `json:"pattern"`
+	Required bool   `json:"required"`
+	Multiple bool   `json:"multiple"`
+
+	// Matches are the files matching the pattern and media type of the output
+	Matches []string `json:"matches"`
+
+	// MediaTypeMismatches are files matching the pattern whose media type differs from the output's
+	MediaTypeMismatches []MediaTypeMismatch `json:"mediaTypeMismatches,omitempty"`
+
+	// MetadataErrors are side-car metadata files of matches that failed validation, with
+	// an entry for each problem found in a file
+	MetadataErrors []MetadataError `json:"metadataErrors,omitempty"`
+
+	// MetadataWarnings are problems found in side-car metadata files that don't fail
+	// validation, such as polygon rings wound the wrong way
+	MetadataWarnings []MetadataError `json:"metadataWarnings,omitempty"`
+
+	// Error is set when too few files were found for the output
+	Error string `json:"error,omitempty"`
+}
+
+//MediaTypeMismatch is an output file whose media type doesn't match the output definition
+type MediaTypeMismatch struct {
+	File      string `json:"file"`
+	MediaType string `json:"mediaType"`
+	Expected  string `json:"expected"`
+}
+
+//MetadataError is a side-car metadata file that failed validation
+type MetadataError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+//OutputJSONError is a problem found validating seed.outputs.json. Key is empty when
+// the problem is with the file as a whole.
+type OutputJSONError struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+//DiskExceeded returns whether the output directory is larger than the job's disk resource
+func (v *OutputValidation) DiskExceeded() bool {
+	return v.DiskLimit > 0 && v.DiskUsage > v.DiskLimit
+}
+
+//Valid returns whether the output passed validation. Media type mismatches alone
+// don't fail validation as the mismatched files are not considered outputs.
+func (v *OutputValidation) Valid() bool {
+	return len(v.Errors()) == 0
+}
+
+//Errors returns a message for each validation failure
+func (v *OutputValidation) Errors() []string {
+	if v == nil {
+		return nil
+	}
+
+	var errs []string
+	if v.DiskExceeded() {
+		errs = append(errs, fmt.Sprintf("Output directory exceeds disk space limit (%f MiB vs. %f MiB)",
+			v.DiskUsage, v.DiskLimit))
+	}
+	for _, f := range v.Files {
+		if f.Error != "" {
+			errs = append(errs, f.Error)
+		}
+		for _, m := range f.MetadataErrors {
+			errs = append(errs, fmt.Sprintf("Side-car metadata file %s validation error: %s", m.File, m.Error))
+		}
+	}
+	for _, j := range v.JSON {
+		errs = append(errs, j.Error)
+	}
+	return errs
+}
+
+//RunOutputs are the outputs a job produced, by the names of the outputs in its seed manifest
+type RunOutputs struct {
+	// Files are the files matching each outputs.files entry
+	Files map[string][]OutputFile `json:"files"`
+
+	// JSON are the values of the outputs.json entries found in seed.outputs.json, read
+	// from the entry's key if it has one. Integers are int64 and numbers float64.
+	JSON map[string]interface{} `json:"json"`
+}
+
+//OutputFile is a file matching an outputs.files entry
+type OutputFile struct {
+	Path string `json:"path"`
+
+	// MetadataFile is the file's side-car metadata file, empty if it has none
+	MetadataFile string `json:"metadataFile,omitempty"`
+
+	// Metadata is the contents of the side-car metadata file, nil if it isn't valid JSON
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+//outputKey returns the key of an outputs.json entry in seed.outputs.json: its key if
+// it has one, otherwise its name
+func outputKey(o objects.OutJson) string {
+	if o.Key != "" {
+		return o.Key
+	}
+	return o.Name
+}
+
+//outputValues returns the values of the outputs.json entries in the seed.outputs.json
+// document data by name. Values missing from the document or not of the entry's type
+// are left out.
+func outputValues(entries []objects.OutJson, data []byte) map[string]interface{} {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	values := map[string]interface{}{}
+	if decoder.Decode(&document) != nil {
+		return values
+	}
+
+	for _, o := range entries {
+		value, ok := document[outputKey(o)]
+		if !ok {
+			continue
+		}
+		if value, ok = outputValue(value, o.Type); ok {
+			values[o.Name] = value
+		}
+	}
+	return values
+}
+
+//outputValue converts a value decoded from seed.outputs.json to the Go type of the JSON
+// schema type outType, returning false if it is of a different type
+func outputValue(value interface{}, outType string) (interface{}, bool) {
+	switch outType {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		// 1.0 is an integer too
+		if f, err := n.Float64(); err == nil && f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 {
+			return int64(f), true
+		}
+		return nil, false
+	case "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		f, err := n.Float64()
+		return f, err == nil
+	case "string":
+		_, ok := value.(string)
+		return value, ok
+	case "boolean":
+		_, ok := value.(bool)
+		return value, ok
+	case "array":
+		_, ok := value.([]interface{})
+		return value, ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return value, ok
+	}
+	return value, true
+}
+
+//readMetadata returns the contents of a side-car metadata file, nil if it can't be
+// read or isn't valid JSON
+func readMetadata(file string) json.RawMessage {
+	data, err := ioutil.ReadFile(file)
+	if err != nil || !json.Valid(data) {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+//PrintRunResult prints the result of a run to stdout as json, with the run's duration
+// in seconds. Nothing is printed in the text format, where the run is logged as it goes.
+func PrintRunResult(result RunResult, format string) error {
+	if format != constants.FormatJSON {
+		return nil
+	}
+	data, err := json.MarshalIndent(struct {
+		RunResult
+		Duration float64 `json:"durationSeconds"`
+	}{result, result.Duration.Seconds()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+	return nil
+}
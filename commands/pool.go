@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bufio"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ngageoint/seed-common/objects"
+)
+
+//resourcePool bounds the cpus and memory used by concurrently running batch jobs
+// so the host is never oversubscribed. A capacity of zero is unlimited.
+type resourcePool struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	cpus float64
+	mem  float64
+
+	usedCPUs float64
+	usedMem  float64
+	running  int
+}
+
+func newResourcePool(cpus, mem float64) *resourcePool {
+	p := &resourcePool{cpus: cpus, mem: mem}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+//acquire blocks until the pool has room for a job needing cpus and mem MiB. A job
+// larger than the whole pool is admitted once nothing else is running.
+func (p *resourcePool) acquire(cpus, mem float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for p.running > 0 && !p.fits(cpus, mem) {
+		p.cond.Wait()
+	}
+	p.usedCPUs += cpus
+	p.usedMem += mem
+	p.running++
+}
+
+//release returns a finished job's cpus and memory to the pool
+func (p *resourcePool) release(cpus, mem float64) {
+	p.mu.Lock()
+	p.usedCPUs -= cpus
+	p.usedMem -= mem
+	p.running--
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+func (p *resourcePool) fits(cpus, mem float64) bool {
+	if p.cpus > 0 && p.usedCPUs+cpus > p.cpus {
+		return false
+	}
+	if p.mem > 0 && p.usedMem+mem > p.mem {
+		return false
+	}
+	return true
+}
+
+//jobResources returns the cpus and memory in MiB a job with the given inputs will be
+// allocated, computed the same way as DefineResources. Resources the manifest doesn't
+// specify are returned as zero.
+func jobResources(seed *objects.Seed, inputs []string, overrides []string) (float64, float64, error) {
+	overrideMap, err := parseResourceOverrides(overrides)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var inputSizeMiB float64
+	for _, values := range fileInputMap(seed, inputs) {
+		inputSizeMiB += inputSize(values)
+	}
+	var cpus, mem float64
+	for _, s := range seed.Job.Resources.Scalar {
+		amount := (s.InputMultiplier * inputSizeMiB) + s.Value
+		amount = applyResourceOverrides(s.Name, amount, overrideMap)
+		if s.Name == "cpus" {
+			cpus = math.Min(math.Max(amount, 0.01), float64(runtime.NumCPU()))
+		}
+		if s.Name == "mem" {
+			mem = math.Ceil(math.Max(amount, 4.0))
+		}
+	}
+	return cpus, mem, nil
+}
+
+//inputSize returns the total size in MiB of the files given by the values of a file
+// input, see resolveInputFiles. Files that can't be found count for nothing; they are
+// reported when the job's inputs are staged.
+func inputSize(values []string) float64 {
+	var size int64
+	paths, _ := resolveInputPaths(values, InputFilter{})
+	for _, path := range paths {
+		size += pathSize(path)
+	}
+	return float64(size) / (1024.0 * 1024.0)
+}
+
+//hostMemoryMiB returns the total memory of the host in MiB, or zero if it can't be determined
+func hostMemoryMiB() float64 {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return 0
+			}
+			return kb / 1024.0
+		}
+	}
+	return 0
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//RunRecord is the provenance of a seed run, written to seed.run.json in the job output directory
+type RunRecord struct {
+	Image   string `json:"image"`
+	ImageID string `json:"imageId,omitempty"`
+
+	// ImageDigest is the registry digest of the image, empty for images never pushed or pulled
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// Manifest is the seed manifest label of the image
+	Manifest json.RawMessage `json:"manifest,omitempty"`
+
+	SeedVersion    string `json:"seedVersion"`
+	JobName        string `json:"jobName"`
+	JobVersion     string `json:"jobVersion"`
+	PackageVersion string `json:"packageVersion"`
+
+	// Command is the container runtime command line and JobCommand the expanded
+	// job.interface.command, both with secret settings redacted
+	Command    string `json:"command"`
+	JobCommand string `json:"jobCommand"`
+
+	Inputs []InputRecord `json:"inputs"`
+
+	// Settings are the settings given to the job, with secret values redacted
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// Mounts are the host paths mounted for the job's mounts by name
+	Mounts map[string]string `json:"mounts,omitempty"`
+
+	// Resources are the allocated scalar resources by name, i.e. cpus and mem in MiB
+	Resources map[string]float64 `json:"resources,omitempty"`
+
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Host     string    `json:"host"`
+	ExitCode int       `json:"exitCode"`
+	TimedOut bool      `json:"timedOut,omitempty"`
+
+	// JobError is the job.errors entry in the manifest matching a non-zero exit code
+	JobError *JobError `json:"jobError,omitempty"`
+
+	Validation *OutputValidation `json:"validation,omitempty"`
+
+	// CacheKey is set when the output was restored from the result cache instead of running the job
+	CacheKey string `json:"cacheKey,omitempty"`
+}
+
+//InputRecord is an input file given to a job. A directory given for an input is
+// recorded as one InputRecord for each file in it.
+type InputRecord struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Error is set when the file couldn't be read to hash it
+	Error string `json:"error,omitempty"`
+}
+
+//NewRunRecord returns the record of a run of imageName with the given inputs, settings
+// and mounts. Settings matching secrets are recorded with their value redacted. filter
+// selects the files of directories given for multiple inputs.
+func NewRunRecord(imageName string, seed *objects.Seed, inputs []string, filter InputFilter, settings, mounts, secrets []string) RunRecord {
+	record := RunRecord{Image: imageName, SeedVersion: seed.SeedVersion, JobName: seed.Job.Name,
+		JobVersion: seed.Job.JobVersion, PackageVersion: seed.Job.PackageVersion}
+	record.Host, _ = os.Hostname()
+
+	if image, err := inspectImage(imageName); err == nil {
+		record.ImageID = image.ID
+		if len(image.RepoDigests) > 0 {
+			record.ImageDigest = image.RepoDigests[0]
+		}
+		record.Manifest = manifestJSON(image.Config.Labels[ManifestLabel])
+	} else {
+		util.PrintUtil("WARNING: Unable to inspect image %s for the run record. %s\n", imageName, err.Error())
+	}
+
+	record.Inputs = inputRecords(seed, inputs, filter)
+
+	secret := map[string]bool{}
+	for _, s := range secrets {
+		secret[util.GetNormalizedVariable(strings.SplitN(s, "=", 2)[0])] = true
+	}
+	inSettings := inputMap(settings, true)
+	for _, s := range seed.Job.Interface.Settings {
+		name := util.GetNormalizedVariable(s.Name)
+		value, ok := inSettings[name]
+		if secret[name] || (s.Secret && ok) {
+			value, ok = RedactedValue, true
+		}
+		if ok {
+			if record.Settings == nil {
+				record.Settings = map[string]string{}
+			}
+			record.Settings[s.Name] = value
+		}
+	}
+
+	inMounts := inputMap(mounts, true)
+	for _, m := range seed.Job.Interface.Mounts {
+		if path, ok := inMounts[util.GetNormalizedVariable(m.Name)]; ok {
+			if record.Mounts == nil {
+				record.Mounts = map[string]string{}
+			}
+			record.Mounts[m.Name] = util.GetFullPath(path, "")
+		}
+	}
+	return record
+}
+
+//manifestJSON returns the manifest label as JSON, or as a JSON string if the label isn't valid JSON
+func manifestJSON(label string) json.RawMessage {
+	if label == "" {
+		return nil
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(label), &v) == nil {
+		return json.RawMessage(label)
+	}
+	data, _ := json.Marshal(label)
+	return data
+}
+
+//inputRecords returns a record with the size and SHA-256 hash of each input file.
+// Multiple inputs may be given lists, patterns and directories, see resolveInputFiles.
+func inputRecords(seed *objects.Seed, inputs []string, filter InputFilter) []InputRecord {
+	inMap := fileInputMap(seed, inputs)
+	records := []InputRecord{}
+	for _, f := range seed.Job.Interface.Inputs.Files {
+		values, ok := inMap[util.GetNormalizedVariable(f.Name)]
+		if !ok {
+			continue
+		}
+		paths := []string{util.GetFullPath(values[0], "")}
+		if f.Multiple {
+			var err error
+			if paths, err = resolveInputPaths(values, filter); err != nil {
+				records = append(records, InputRecord{Name: f.Name, Path: strings.Join(values, ", "), Error: err.Error()})
+				continue
+			}
+		}
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				records = append(records, InputRecord{Name: f.Name, Path: path, Error: err.Error()})
+				continue
+			}
+			if !info.IsDir() {
+				records = append(records, inputRecord(f.Name, path, info))
+				continue
+			}
+			filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+				if err != nil {
+					records = append(records, InputRecord{Name: f.Name, Path: file, Error: err.Error()})
+				} else if info.Mode().IsRegular() {
+					records = append(records, inputRecord(f.Name, file, info))
+				}
+				return nil
+			})
+		}
+	}
+	return records
+}
+
+//inputRecord returns the record of one input file with its size and SHA-256 hash
+func inputRecord(name, path string, info os.FileInfo) InputRecord {
+	record := InputRecord{Name: name, Path: path, Size: info.Size()}
+	file, err := os.Open(path)
+	if err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		record.Error = err.Error()
+		return record
+	}
+	record.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return record
+}
+
+//WriteRunRecord writes the run record to seed.run.json in the output directory
+func WriteRunRecord(record RunRecord, outputDir string) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(outputDir, constants.RunRecordFile), data, 0644)
+}
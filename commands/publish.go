@@ -1,17 +1,13 @@
 package commands
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/constants"
@@ -40,7 +36,7 @@ func DockerPublish(origImg, manifest, registry, org, username, password, jobDire
 		return "", err
 	}
 
-	if exists, err := util.ImageExists(origImg); !exists {
+	if exists, err := imageExists(origImg); !exists {
 		if err != nil {
 			util.PrintUtil("%s\n", err.Error())
 			return "", err
@@ -58,14 +54,10 @@ func DockerPublish(origImg, manifest, registry, org, username, password, jobDire
 	repoName := temp[0]
 	repoTag := temp[1]
 
-	if username != "" {
-		//set config dir so we don't stomp on other users' logins with sudo
-		configDir := common_const.DockerConfigDir + time.Now().Format(time.RFC3339)
-		os.Setenv(common_const.DockerConfigKey, configDir)
-		defer util.RemoveAllFiles(configDir)
-		defer os.Unsetenv(common_const.DockerConfigKey)
-
-		err := util.Login(registry, username, password)
+	auth := registryAuth(registry, username, password)
+	if username != "" && cliutil.DockerClient() == nil {
+		cleanup, err := registryLogin(registry, username, password)
+		defer cleanup()
 		if err != nil {
 			util.PrintUtil(err.Error())
 		}
@@ -220,32 +212,10 @@ func DockerPublish(origImg, manifest, registry, org, username, password, jobDire
 
 		// Build Docker image
 		util.PrintUtil("INFO: Building %s\n", img)
-		var buildArgs, dockerCommand = cliutil.DockerCommandArgsInit()
-		buildArgs = append(buildArgs, "build", "-t", img, jobDirectory)
-		if util.DockerVersionHasLabel() {
-			// Set the seed.manifest.json contents as an image label
-			label := "com.ngageoint.seed.manifest=" + objects.GetManifestLabel(seedFileName)
-			buildArgs = append(buildArgs, "--label", label)
-		}
-		util.PrintUtil("INFO: Running Docker command:\n%s %s\n", dockerCommand, strings.Join(buildArgs, " "))
-		rebuildCmd := exec.Command(dockerCommand, buildArgs...)
-		var errs bytes.Buffer
-		if util.StdErr != nil {
-			rebuildCmd.Stderr = io.MultiWriter(util.StdErr, &errs)
-		} else {
-			rebuildCmd.Stderr = &errs
-		}
-		rebuildCmd.Stdout = util.StdErr
-
-		// Run docker build
-		rebuildCmd.Run()
-
-		// check for errors on stderr
-		if errs.String() != "" {
-			util.PrintUtil("ERROR: Error re-building image '%s':\n%s\n",
-				img, errs.String())
+		if err = buildImage(img, jobDirectory, ".", "", seedFileName, nil); err != nil {
+			util.PrintUtil("ERROR: Error re-building image '%s'\n", img)
 			util.PrintUtil("Exiting seed...\n")
-			return "", errors.New(errs.String())
+			return "", err
 		}
 
 		// Set final image name to tag + image
@@ -253,17 +223,17 @@ func DockerPublish(origImg, manifest, registry, org, username, password, jobDire
 		img = tag + img
 	}
 
-	err := util.Tag(origImg, img)
+	err := tagImage(origImg, img)
 	if err != nil {
 		return img, err
 	}
 
-	err = util.Push(img)
+	err = pushImage(img, auth)
 	if err != nil {
 		return img, err
 	}
 
-	err = util.RemoveImage(img)
+	err = removeImage(img)
 	if err != nil {
 		return img, err
 	}
@@ -1,14 +1,9 @@
 package commands
 
 import (
-	"bytes"
-	"errors"
 	"fmt"
-	"io"
-	"os"
 	"os/exec"
 	"strings"
-	"time"
 
 	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/constants"
@@ -18,20 +13,17 @@ import (
 
 //Dockerpull pulls specified image from remote repository (default docker.io)
 func DockerPull(image, registry, org, username, password string) error {
-	if username != "" {
-		//set config dir so we don't stomp on other users' logins with sudo
-		configDir := common_const.DockerConfigDir + time.Now().Format(time.RFC3339)
-		os.Setenv(common_const.DockerConfigKey, configDir)
-		defer util.RemoveAllFiles(configDir)
-		defer os.Unsetenv(common_const.DockerConfigKey)
-
-		err := util.Login(registry, username, password)
+	client := cliutil.DockerClient()
+	if username != "" && client == nil {
+		cleanup, err := registryLogin(registry, username, password)
+		defer cleanup()
 		if err != nil {
 			util.PrintUtil(err.Error())
 			return err
 		}
 	}
 
+	auth := registryAuth(registry, username, password)
 	if registry == "" {
 		registry = common_const.DefaultRegistry
 	}
@@ -42,18 +34,29 @@ func DockerPull(image, registry, org, username, password string) error {
 		remoteImage = fmt.Sprintf("%s/%s/%s", registry, org, image)
 	}
 
-	var errs, out bytes.Buffer
+	if client != nil {
+		util.PrintUtil("INFO: Pulling %s\n", remoteImage)
+		if err := client.PullImage(remoteImage, auth, util.StdErr); err != nil {
+			util.PrintUtil("ERROR: Error pulling image.\n%s\n", err.Error())
+			return err
+		}
+		util.PrintUtil("INFO: Tagging %s as %s\n", remoteImage, image)
+		if err := client.TagImage(remoteImage, image); err != nil {
+			util.PrintUtil("ERROR: Error tagging image.\n%s\n", err.Error())
+			return err
+		}
+		return nil
+	}
+
+	// docker writes progress to stderr, so only the exit codes tell us whether the commands failed
 	// pull image
-	var pullArgs, dockerCommand = cliutil.DockerCommandArgsInit()
+	var dockerArgs, dockerCommand = cliutil.CommandArgsInit()
+	pullArgs := append([]string{}, dockerArgs...)
 	pullArgs = append(pullArgs, "pull", remoteImage)
-	util.PrintUtil("INFO: Running Docker command:\n%s %s\n", dockerCommand, strings.Join(pullArgs, " "))
+	util.PrintUtil("INFO: Running %s command:\n%s %s\n", cliutil.CurrentRuntime().Name(), dockerCommand, strings.Join(pullArgs, " "))
 	pullCmd := exec.Command(dockerCommand, pullArgs...)
-	if util.StdErr != nil {
-		pullCmd.Stderr = io.MultiWriter(util.StdErr, &errs)
-	} else {
-		pullCmd.Stderr = &errs
-	}
-	pullCmd.Stdout = &out
+	pullCmd.Stderr = util.StdErr
+	pullCmd.Stdout = util.StdErr
 
 	err := pullCmd.Run()
 	if err != nil {
@@ -62,23 +65,14 @@ func DockerPull(image, registry, org, username, password string) error {
 		return err
 	}
 
-	if errs.String() != "" {
-		util.PrintUtil("ERROR: Error reading stderr %s\n",
-			errs.String())
-		return errors.New(errs.String())
-	}
-
 	// tag image
-	tagArgs := []string{"tag", remoteImage, image}
-	tagCmd := exec.Command("docker", tagArgs...)
-	if util.StdErr != nil {
-		tagCmd.Stderr = io.MultiWriter(util.StdErr, &errs)
-	} else {
-		tagCmd.Stderr = &errs
-	}
-	tagCmd.Stdout = &out
+	tagArgs := append([]string{}, dockerArgs...)
+	tagArgs = append(tagArgs, "tag", remoteImage, image)
+	tagCmd := exec.Command(dockerCommand, tagArgs...)
+	tagCmd.Stderr = util.StdErr
+	tagCmd.Stdout = util.StdErr
 
-	util.PrintUtil("INFO: Running Docker command:\ndocker %s\n", strings.Join(tagArgs, " "))
+	util.PrintUtil("INFO: Running %s command:\n%s %s\n", cliutil.CurrentRuntime().Name(), dockerCommand, strings.Join(tagArgs, " "))
 	err = tagCmd.Run()
 	if err != nil {
 		util.PrintUtil("ERROR: Error executing docker tag.\n%s\n",
@@ -86,12 +80,6 @@ func DockerPull(image, registry, org, username, password string) error {
 		return err
 	}
 
-	if errs.String() != "" {
-		util.PrintUtil("ERROR: Error reading stderr %s\n",
-			errs.String())
-		return errors.New(errs.String())
-	}
-
 	return nil
 }
 
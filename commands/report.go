@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+//Batch report formats
+const (
+	ReportJSON  = "json"
+	ReportCSV   = "csv"
+	ReportJUnit = "junit"
+)
+
+//BatchReport summarizes the outcome of every row of a batch
+type BatchReport struct {
+	Image     string       `json:"image"`
+	Finished  time.Time    `json:"finished"`
+	Duration  float64      `json:"durationSeconds"`
+	Total     int          `json:"total"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+	Rows      []JournalRow `json:"rows"`
+}
+
+//NewBatchReport summarizes the rows recorded in the batch journal
+func NewBatchReport(journal *BatchJournal, duration time.Duration) BatchReport {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	report := BatchReport{
+		Image:    journal.Image,
+		Finished: time.Now(),
+		Duration: duration.Seconds(),
+		Total:    len(journal.Rows),
+		Rows:     append([]JournalRow{}, journal.Rows...),
+	}
+	for _, row := range report.Rows {
+		if row.Status == RowSucceeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+//WriteBatchReport writes the report to dir in the given format, json by default.
+// Returns the path of the report file.
+func WriteBatchReport(report BatchReport, dir, format string) (string, error) {
+	if format == "" {
+		format = ReportJSON
+	}
+
+	var data []byte
+	var err error
+	ext := format
+	switch format {
+	case ReportJSON:
+		data, err = json.MarshalIndent(report, "", "  ")
+	case ReportCSV:
+		data, err = batchReportCSV(report)
+	case ReportJUnit:
+		ext = "xml"
+		data, err = batchReportJUnit(report)
+	default:
+		return "", fmt.Errorf("ERROR: Unknown report format %s. Supported formats are %s, %s and %s",
+			format, ReportJSON, ReportCSV, ReportJUnit)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, constants.BatchReportFile+"."+ext)
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+func batchReportCSV(report BatchReport) ([]byte, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write([]string{"inputs", "json", "outdir", "status", "exitCode", "timedOut", "errorName", "errorTitle",
+		"errorCategory", "validation", "attempts", "durationSeconds", "error"})
+	for _, row := range report.Rows {
+		var name, title, category string
+		if row.JobError != nil {
+			name, title, category = row.JobError.Name, row.JobError.Title, row.JobError.Category
+		}
+		w.Write([]string{
+			strings.Join(row.Inputs, ";"),
+			strings.Join(row.Json, ";"),
+			row.Outdir,
+			row.Status,
+			strconv.Itoa(row.ExitCode),
+			strconv.FormatBool(row.TimedOut),
+			name,
+			title,
+			category,
+			strings.Join(row.Validation, ";"),
+			strconv.Itoa(row.Attempts),
+			strconv.FormatFloat(row.Duration, 'f', 3, 64),
+			row.Error,
+		})
+	}
+	w.Flush()
+	return []byte(b.String()), w.Error()
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func batchReportJUnit(report BatchReport) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      report.Image,
+		Tests:     report.Total,
+		Failures:  report.Failed,
+		Time:      strconv.FormatFloat(report.Duration, 'f', 3, 64),
+		Timestamp: report.Finished.Format("2006-01-02T15:04:05"),
+	}
+
+	for _, row := range report.Rows {
+		testCase := junitTestCase{
+			Name:      strings.Join(append(append([]string{}, row.Inputs...), row.Json...), " "),
+			ClassName: report.Image,
+			Time:      strconv.FormatFloat(row.Duration, 'f', 3, 64),
+		}
+
+		out := []string{"Output directory: " + row.Outdir}
+		for _, v := range row.Validation {
+			out = append(out, "Validation: "+v)
+		}
+		testCase.SystemOut = strings.Join(out, "\n")
+
+		if row.Status != RowSucceeded {
+			failure := &junitFailure{Message: row.Error, Text: fmt.Sprintf("Exit code %d", row.ExitCode)}
+			if row.Status == RowPending {
+				failure.Message = "Job did not run (" + row.Status + ")"
+			}
+			if row.JobError != nil {
+				failure.Type = row.JobError.Category
+				failure.Text += fmt.Sprintf(": %s (%s)\n%s", row.JobError.Title, row.JobError.Name, row.JobError.Description)
+			}
+			testCase.Failure = failure
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	data, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
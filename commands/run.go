@@ -14,14 +14,18 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-cli/dockerapi"
 	"github.com/ngageoint/seed-cli/streampainter"
 	common_const "github.com/ngageoint/seed-common/constants"
 	"github.com/ngageoint/seed-common/objects"
@@ -29,54 +33,167 @@ import (
 	"github.com/xeipuuv/gojsonschema"
 )
 
-//DockerRun Runs image described by Seed spec
-func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, json, settings, mounts []string, rmDir, quiet bool) (int, error) {
-	util.InitPrinter(util.PrintErr, os.Stderr, os.Stderr)
-	if quiet {
-		util.InitPrinter(util.Quiet, nil, nil)
-	}
+//RunOptions holds the optional settings for a seed run that are not defined by
+// the seed manifest itself
+type RunOptions struct {
+	// Timeout in seconds; overrides job.timeout from the manifest when greater than zero
+	Timeout int
+
+	// Resources overrides applied to the scalar resources in the manifest, see DefineResources
+	Resources []string
+
+	// CPUSet pins the container to specific CPUs instead of limiting its CPU time
+	CPUSet bool
+
+	// SecretsFile is a file of KEY=VALUE pairs for the secret settings in the manifest
+	SecretsFile string
+
+	// Parallel is the number of batch jobs to run at once
+	Parallel int
+
+	// Resume skips batch jobs the batch journal records as succeeded
+	Resume bool
+
+	// RetryFailed is the number of times failed batch jobs are retried
+	RetryFailed int
+
+	// Report is the format of the batch report: json, csv or junit
+	Report string
+
+	// StrictOutputs fails the run when its output fails validation
+	StrictOutputs bool
+
+	// MediaTypes is the policy for input files whose media type isn't in the manifest:
+	// warn (the default), fail or off
+	MediaTypes string
+
+	// DryRun prints the prepared docker run command instead of running it
+	DryRun bool
+
+	// OutputFormat is the format a dry run is printed in: text or json
+	OutputFormat string
+
+	// Debug starts an interactive shell in the prepared job container instead of running the job
+	Debug bool
+
+	// LogTimestamps prefixes each line of stdout.log and stderr.log with the time it was written
+	LogTimestamps bool
+
+	// LogPrefix is written before each line of the job's stderr printed to the console,
+	// i.e. "[job-3] " for the third job of a parallel batch
+	LogPrefix string
+
+	// NoCache runs the job without looking up or storing its output in the result cache
+	NoCache bool
+
+	// InputFilter selects the files of directories given for multiple file inputs
+	InputFilter InputFilter
+
+	// JobSpec is the job spec file given with -f, validated against the seed manifest
+	JobSpec *JobSpec
+}
+
+//RunResult describes the outcome of a seed run
+type RunResult struct {
+	// ExitCode is the job's exit code, -1 if the job did not run or exit normally
+	ExitCode int `json:"exitCode"`
+
+	// JobError is the job.errors entry in the manifest matching a non-zero exit code
+	JobError *JobError `json:"jobError,omitempty"`
+
+	// OutputDir is the job output directory the job wrote to
+	OutputDir string `json:"outputDir"`
+
+	Duration time.Duration `json:"-"`
+	TimedOut bool          `json:"timedOut"`
+
+	// Validation is the result of validating the job's output, nil if the manifest defines no outputs
+	Validation *OutputValidation `json:"validation,omitempty"`
+
+	// Outputs are the output files and JSON values the job produced, nil if the manifest
+	// defines no outputs
+	Outputs *RunOutputs `json:"outputs,omitempty"`
+
+	// Cached is set when the output was restored from the result cache instead of running the job
+	Cached bool `json:"cached"`
+}
+
+//JobError is an entry from the job.errors section of a seed manifest
+type JobError struct {
+	Code        int    `json:"code"`
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+//DockerRun Runs image described by Seed spec and returns the outcome of the run,
+// including the outputs the job produced
+func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, json, settings, mounts []string, rmDir bool, opts RunOptions) (RunResult, error) {
+	var result RunResult
 
 	if imageName == "" {
 		util.PrintUtil("INFO: Image name not specified. Attempting to use manifest: %v\n", manifest)
 		temp, err := objects.GetImageNameFromManifest(manifest, "")
 		if err != nil {
-			return 0, err
+			return result, err
 		}
 		imageName = temp
 	}
 
 	if imageName == "" {
-		return 0, errors.New("ERROR: No input image specified.")
+		return result, errors.New("ERROR: No input image specified.")
 	}
 
-	if exists, err := util.ImageExists(imageName); !exists {
+	if exists, err := imageExists(imageName); !exists {
 		msg := fmt.Sprintf("Unable to find image: %s. Did you specify a valid tag?", imageName)
 		util.PrintUtil("%s\n", msg)
-		return 0, err
+		return result, err
 	}
 
 	// Parse seed information off of the label
 	seed := objects.SeedFromImageLabel(imageName)
 
+	if opts.JobSpec != nil {
+		if err := opts.JobSpec.Validate(&seed, false); err != nil {
+			return result, err
+		}
+	}
+
 	// build docker run command
-	var dockerArgs, dockerCommand = cliutil.DockerCommandArgsInit()
+	var dockerArgs, dockerCommand = cliutil.CommandArgsInit()
 	dockerArgs = append(dockerArgs, "run")
+	runStart := len(dockerArgs)
 	if rmDir {
 		dockerArgs = append(dockerArgs, "--rm")
 	}
 
+	// name the container so it can be stopped if the job times out
+	containerName := ContainerName(&seed)
+	dockerArgs = append(dockerArgs, "--name", containerName)
+
+	// the -timeout flag overrides job.timeout from the manifest
+	timeout := seed.Job.Timeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+
 	var mountsArgs []string
 	var envArgs []string
 	var resourceArgs []string
 	var inputSize float64
 	var outputSize float64
+	var staged map[string]string
 	var errors error
 
 	// expand INPUT_FILEs to specified Inputs files
 	if seed.Job.Interface.Inputs.Files != nil {
-		inMounts, size, temp, err := DefineInputs(&seed, inputs)
-		for _, v := range temp {
-			defer util.RemoveAllFiles(v)
+		inMounts, size, staging, err := defineInputs(&seed, inputs, opts.InputFilter, !opts.DryRun)
+		defer cleanup(staging.Cleanup)()
+		staged = staging.ContainerDirs()
+		if err == nil {
+			// wrong input files fail now rather than after the container starts
+			err = CheckInputMediaTypes(&seed, inputs, opts.InputFilter, opts.MediaTypes)
 		}
 		if err != nil {
 			errors = fmt.Errorf("\nERROR: Error occurred processing inputs arguments.\n%v", err)
@@ -97,7 +214,7 @@ func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, js
 	}
 
 	if len(seed.Job.Resources.Scalar) > 0 {
-		inResources, diskSize, err := DefineResources(&seed, inputSize)
+		inResources, diskSize, err := DefineResources(&seed, inputSize, opts.Resources, opts.CPUSet)
 		if err != nil {
 			errors = fmt.Errorf("%v\nERROR: Error occurred processing resources.\n%v", errors, err)
 		} else if inResources != nil {
@@ -108,7 +225,7 @@ func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, js
 
 	// mount the JOB_OUTPUT_DIR (outDir flag)
 	var outDir string
-	outDir = SetOutputDir(imageName, &seed, outputDir)
+	outDir = resolveOutputDir(imageName, &seed, outputDir, !opts.DryRun)
 	if outDir != "" {
 		mountsArgs = append(mountsArgs, "-v")
 		mountsArgs = append(mountsArgs, outDir+":"+outDir)
@@ -119,13 +236,24 @@ func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, js
 	}
 
 	// Settings
+	// secret settings are loaded from the environment and secrets file first so
+	// any given with -e take precedence
+	var secrets []string
+	var allSettings []string
 	if seed.Job.Interface.Settings != nil {
-		inSettings, err := DefineSettings(&seed, settings)
+		loaded, err := LoadSecrets(&seed, opts.SecretsFile)
+		if err != nil {
+			errors = fmt.Errorf("%v\nERROR: Error occurred loading secret settings.\n%v", errors, err)
+		}
+		allSettings = append(loaded, settings...)
+		inSettings, inSecrets, err := DefineSettings(&seed, allSettings)
 		if err != nil {
 			errors = fmt.Errorf("%v\nERROR: Error occurred processing settings arguments.\n%v", errors, err)
 		} else if inSettings != nil {
 			envArgs = append(envArgs, inSettings...)
 		}
+		secrets = inSecrets
+		WarnShortSecrets(secrets)
 	}
 
 	// Additional Mounts defined in seed.json
@@ -138,8 +266,32 @@ func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, js
 		}
 	}
 
+	// expand the inputs, settings and OUTPUT_DIR in job.interface.command into its arguments
+	ctx := NewCommandContext(&seed, inputs, json, allSettings, staged, outDir)
+	args, commandErr := ExpandCommand(seed.Job.Interface.Command, ctx)
+	if commandErr != nil {
+		errors = fmt.Errorf("%v\nERROR: Error occurred processing the job command.\n%v", errors, commandErr)
+	}
+
+	result.OutputDir = outDir
+	result.ExitCode = -1
 	if errors != nil {
-		return -1, errors
+		return result, errors
+	}
+
+	// pass secret settings through an env file so they don't show up in the process list
+	if len(secrets) > 0 && opts.DryRun {
+		// a dry run shows which secrets are set without writing them anywhere
+		for _, s := range secrets {
+			envArgs = append(envArgs, "-e", strings.SplitN(s, "=", 2)[0]+"="+RedactedValue)
+		}
+	} else if len(secrets) > 0 {
+		envFile, err := WriteEnvFile(secrets)
+		if err != nil {
+			return result, fmt.Errorf("ERROR: Error occurred writing secret settings.\n%v", err)
+		}
+		defer cleanup(func() { os.Remove(envFile) })()
+		envArgs = append(envArgs, "--env-file", envFile)
 	}
 
 	// Build Docker command arguments:
@@ -152,64 +304,292 @@ func DockerRun(imageName, manifest, outputDir, metadataSchema string, inputs, js
 	dockerArgs = append(dockerArgs, mountsArgs...)
 	dockerArgs = append(dockerArgs, envArgs...)
 	dockerArgs = append(dockerArgs, resourceArgs...)
-	dockerArgs = append(dockerArgs, imageName)
 
-	// Parse out command arguments from seed.Job.Interface.Command
-	args := strings.Split(seed.Job.Interface.Command, " ")
-	dockerArgs = append(dockerArgs, args...)
+	// a debug run starts a shell in place of the job with a script that runs the job
+	var debug debugSession
+	if opts.Debug {
+		image, err := inspectImage(imageName)
+		if err != nil {
+			return result, err
+		}
+		runArgs := append(append([]string{}, mountsArgs...), envArgs...)
+		debug, err = prepareDebugSession(imageName, containerName, &seed, image.Config.Entrypoint, args, runArgs, secrets, !opts.DryRun)
+		if !opts.DryRun {
+			dir := debug.Dir
+			defer cleanup(func() { util.RemoveAllFiles(dir) })()
+		}
+		if err != nil {
+			return result, err
+		}
+		dockerArgs = append(dockerArgs, debug.Args...)
+		dockerArgs = append(dockerArgs, imageName)
+	} else {
+		dockerArgs = append(dockerArgs, imageName)
+		dockerArgs = append(dockerArgs, args...)
+	}
+
+	if opts.DryRun {
+		result.ExitCode = 0
+		plan := RunPlan{Image: imageName, Runtime: cliutil.CurrentRuntime().Name(), ContainerName: containerName,
+			Command: dockerCommand, Args: dockerArgs, JobCommand: shellQuote(args), OutputDir: outDir,
+			Resources: allocatedResources(resourceArgs), DiskLimit: outputSize, Timeout: timeout}
+		return result, PrintRunPlan(plan, opts.OutputFormat)
+	}
 
 	// Run
-	util.PrintUtil("INFO: Running Docker command:\n%s %s\n", dockerCommand, strings.Join(dockerArgs, " "))
+	util.PrintUtil("INFO: Running %s command:\n%s %s\n", cliutil.CurrentRuntime().Name(), dockerCommand,
+		RedactSecrets(strings.Join(dockerArgs, " "), secrets))
+
+	// only the end of stderr is kept in memory to print once the job ends; all of it is
+	// written to stderr.log
+	errs := newTailBuffer(constants.StderrBufferSize)
+	stdouts := []io.Writer{}
+	stderrs := []io.Writer{errs}
+	// secrets are redacted from the output printed as the job runs
+	painter := streampainter.NewStreamPainter(os.Stderr, color.FgRed)
+	painter.SetPrefix(opts.LogPrefix)
+	live := []*redactWriter{newRedactWriter(painter, secrets)}
+	stderrs = append(stderrs, live[0])
+	if util.StdOut != nil {
+		live = append(live, newRedactWriter(util.StdOut, secrets))
+		stdouts = append(stdouts, live[1])
+	}
+	if cliutil.Interrupted() {
+		return result, fmt.Errorf("ERROR: seed was interrupted before the job started")
+	}
+	// if seed is interrupted, stop the container, removing it if -rm was given
+	defer cliutil.OnInterrupt(func() {
+		util.PrintUtil("INFO: Interrupted. Stopping container %s...\n", containerName)
+		stopContainer(containerName, rmDir)
+	})()
+
+	if opts.Debug {
+		util.PrintUtil("%s\n", RedactSecrets(debug.Banner, secrets))
+		exitCode, err := runDebugShell(dockerCommand, dockerArgs)
+		result.ExitCode = exitCode
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("ERROR: Debug shell exited with code %d", exitCode)
+		}
+		return result, err
+	}
+
+	// record the provenance of the run alongside its output, however the run ends
+	record := NewRunRecord(imageName, &seed, inputs, opts.InputFilter, settings, mounts, secrets)
+	record.Command = RedactSecrets(dockerCommand+" "+strings.Join(dockerArgs, " "), secrets)
+	record.JobCommand = RedactSecrets(shellQuote(args), secrets)
+	record.Resources = allocatedResources(resourceArgs)
+	if outDir != "" {
+		defer func() {
+			record.End = time.Now()
+			record.ExitCode = result.ExitCode
+			record.TimedOut = result.TimedOut
+			record.JobError = result.JobError
+			record.Validation = result.Validation
+			if err := WriteRunRecord(record, outDir); err != nil {
+				util.PrintUtil("WARNING: Error writing %s.\n%v\n", constants.RunRecordFile, err)
+			}
+		}()
+	}
 
-	// Run Docker command and capture output
-	dockerRun := exec.Command(dockerCommand, dockerArgs...)
-	var errs bytes.Buffer
-	dockerRun.Stderr = io.MultiWriter(&errs, streampainter.NewStreamPainter(color.FgRed))
-	dockerRun.Stdout = util.StdOut
+	// restore the output of an identical earlier run from the result cache
+	record.Start = time.Now()
+	cacheDir := CacheDir()
+	cacheKey := ""
+	if cacheDir != "" && !opts.NoCache {
+		cacheKey = ResultCacheKey(record, json, metadataSchema)
+		if len(record.Mounts) > 0 && !CacheMounts() {
+			util.PrintUtil("INFO: Not using the result cache for a job with mounts. Set %s to cache it anyway.\n",
+				constants.CacheMountsEnv)
+		}
+	}
+	if entry, ok := lookupCache(cacheDir, cacheKey); ok {
+		util.PrintUtil("INFO: Restoring output of %s from the result cache (%s)\n", imageName, cacheKey[:12])
+		if err := restoreCache(entry, outDir); err != nil {
+			util.PrintUtil("WARNING: Error restoring cached output; running the job.\n%v\n", err)
+		} else {
+			record.CacheKey = cacheKey
+			result.ExitCode = 0
+			result.Cached = true
+			if seed.Job.Interface.Outputs.Files != nil || seed.Job.Interface.Outputs.JSON != nil {
+				result.Validation, result.Outputs = CheckRunOutput(&seed, outDir, metadataSchema, outputSize)
+				if opts.StrictOutputs && !result.Validation.Valid() {
+					return result, fmt.Errorf("ERROR: Output validation failed for %s:\n\t%s", imageName,
+						strings.Join(result.Validation.Errors(), "\n\t"))
+				}
+			}
+			return result, nil
+		}
+	}
+
+	// tee the job's output to stdout.log and stderr.log, even when it isn't printed
+	var logs *jobLogs
+	if outDir != "" {
+		var err error
+		logs, err = openJobLogs(outDir, opts.LogTimestamps, secrets)
+		if err != nil {
+			return result, fmt.Errorf("ERROR: Error creating job log files.\n%v", err)
+		}
+		defer logs.Close()
+		stdouts = append(stdouts, logs.Stdout)
+		stderrs = append(stderrs, logs.Stderr)
+	}
+	stdout := io.MultiWriter(stdouts...)
+	stderr := io.MultiWriter(stderrs...)
 
-	// Run docker run
 	runTime := time.Now()
-	err := dockerRun.Run()
-	util.TimeTrack(runTime, "INFO: "+imageName+" run")
+	record.Start = runTime
 	exitCode := 0
-	if err != nil {
-		exitError, ok := err.(*exec.ExitError)
-		if ok {
+	timedOut := false
+	var err error
+	// Run the container through the Docker Engine API if we can reach it and fall back
+	// to running the docker command if not, or for docker run options the API client
+	// doesn't translate
+	var run *dockerapi.RunConfig
+	client := cliutil.DockerClient()
+	if client != nil {
+		if run, err = dockerapi.ConfigFromRunArgs(dockerArgs[runStart:]); err != nil {
+			util.PrintUtil("INFO: Running the %s command instead of using the Docker Engine API: %s\n", dockerCommand, err.Error())
+			err = nil
+		}
+	}
+	if run != nil {
+		exitCode, timedOut, err = runContainer(client, run, stdout, stderr, timeout)
+		if err == nil && exitCode != 0 && !timedOut {
+			err = fmt.Errorf("exit status %d", exitCode)
+		}
+	} else {
+		dockerRun := exec.Command(dockerCommand, dockerArgs...)
+		dockerRun.Stderr = stderr
+		dockerRun.Stdout = stdout
+
+		err = dockerRun.Start()
+		if err == nil {
+			timedOut, err = waitForContainer(dockerRun, containerName, timeout)
+		}
+		if exitError, ok := err.(*exec.ExitError); ok {
 			ws := exitError.Sys().(syscall.WaitStatus)
 			exitCode = ws.ExitStatus()
-			util.PrintUtil("Exited with error code %v\n", exitCode)
-			match := false
-			for _, e := range seed.Job.Errors {
-				if e.Code == exitCode {
-					util.PrintUtil("Title: \t %s\n", e.Title)
-					util.PrintUtil("Description: \t %s\n", e.Description)
-					util.PrintUtil("Category: \t %s \n \n", e.Category)
-					match = true
-					util.PrintUtil("Exiting seed...\n")
-					return exitCode, err
-				}
-			}
-			if !match {
-				util.PrintUtil("No matching error code found in Seed manifest\n")
+		} else if err != nil {
+			exitCode = -1
+		}
+	}
+	for _, w := range live {
+		w.Flush()
+	}
+	painter.Flush()
+	// the logs are complete before the output is validated and cached
+	if logs != nil {
+		if err := logs.Close(); err != nil {
+			util.PrintUtil("WARNING: Error writing the job logs.\n%v\n", err)
+		}
+	}
+	util.TimeTrack(runTime, "INFO: "+imageName+" run")
+	result.Duration = time.Since(runTime)
+	result.TimedOut = timedOut
+	if timedOut {
+		err = fmt.Errorf("ERROR: %s exceeded the job timeout of %d seconds", imageName, timeout)
+		util.PrintUtil("%s\n", err.Error())
+	} else if exitCode == -1 {
+		util.PrintUtil("ERROR: error executing docker run. %s\n",
+			err.Error())
+	} else if exitCode != 0 {
+		util.PrintUtil("Exited with error code %v\n", exitCode)
+		// the output of a failed job is still validated
+		for _, e := range seed.Job.Errors {
+			if e.Code == exitCode {
+				util.PrintUtil("Title: \t %s\n", e.Title)
+				util.PrintUtil("Description: \t %s\n", e.Description)
+				util.PrintUtil("Category: \t %s \n \n", e.Category)
+				result.JobError = &JobError{Code: e.Code, Name: e.Name, Title: e.Title,
+					Description: e.Description, Category: e.Category}
+				break
 			}
-		} else {
-			util.PrintUtil("ERROR: error executing docker run. %s\n",
-				err.Error())
+		}
+		if result.JobError == nil {
+			util.PrintUtil("No matching error code found in Seed manifest\n")
 		}
 	}
 
-	if errs.String() != "" {
+	stderrLog := ""
+	if outDir != "" {
+		stderrLog = filepath.Join(outDir, constants.StderrLogFile)
+	}
+	if summary := errs.Summary(stderrLog); summary != "" {
 		util.PrintUtil("stderr for '%s':\n%s\n",
-			imageName, errs.String())
+			imageName, RedactSecrets(summary, secrets))
 	}
 
 	// Validate output against pattern
 	if seed.Job.Interface.Outputs.Files != nil ||
 		seed.Job.Interface.Outputs.JSON != nil {
-		CheckRunOutput(&seed, outDir, metadataSchema, outputSize)
+		if timedOut {
+			util.PrintUtil("INFO: Validating partial output of timed out job...\n")
+		}
+		result.Validation, result.Outputs = CheckRunOutput(&seed, outDir, metadataSchema, outputSize)
+		if opts.StrictOutputs && err == nil && !result.Validation.Valid() {
+			err = fmt.Errorf("ERROR: Output validation failed for %s:\n\t%s", imageName,
+				strings.Join(result.Validation.Errors(), "\n\t"))
+		}
 	}
 
-	return exitCode, err
+	// only the output of runs that succeeded is cached
+	if cacheKey != "" && err == nil && exitCode == 0 && result.Validation.Valid() {
+		if err := storeCache(cacheDir, cacheKey, record, outDir); err != nil {
+			util.PrintUtil("WARNING: Error storing output in the result cache.\n%v\n", err)
+		}
+	}
+
+	result.ExitCode = exitCode
+	return result, err
+}
+
+//cleanup registers f to run if seed is interrupted and returns a function that runs f
+// and unregisters it, to be deferred
+func cleanup(f func()) func() {
+	remove := cliutil.OnInterrupt(f)
+	return func() {
+		remove()
+		f()
+	}
+}
+
+//containerCount makes container names unique when jobs are started at the same time
+var containerCount uint64
+
+//ContainerName returns a unique name for a container running the given seed job
+// in the form seed-<job name>-<id>
+func ContainerName(seed *objects.Seed) string {
+	id := strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(atomic.AddUint64(&containerCount, 1), 36)
+	return "seed-" + seed.Job.Name + "-" + id
+}
+
+//waitForContainer waits for the started docker run command to exit. If timeout is
+// greater than zero and the job runs longer than timeout seconds, the container is
+// stopped and killed. Returns whether the job timed out and any error from the command.
+func waitForContainer(cmd *exec.Cmd, containerName string, timeout int) (bool, error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	if timeout <= 0 {
+		return false, <-done
+	}
+
+	select {
+	case err := <-done:
+		return false, err
+	case <-time.After(time.Duration(timeout) * time.Second):
+		util.PrintUtil("INFO: Job timeout of %d seconds reached. Stopping container %s...\n",
+			timeout, containerName)
+		if err := cliutil.StopContainer(containerName, constants.StopGracePeriod); err != nil {
+			util.PrintUtil("ERROR: Error stopping container %s: %s\n", containerName, err.Error())
+			// make sure we don't wait on a docker client that is never going to exit
+			cmd.Process.Kill()
+		}
+		<-done
+		return true, nil
+	}
 }
 
 func ListDir(path string) {
@@ -228,35 +608,33 @@ func ListDir(path string) {
 // flags 'inputs' and sets the path in the json object. Returns:
 // 	[]string: docker command args for input files in the format:
 //	"-v /path/to/file1:/path/to/file1 -v /path/to/file2:/path/to/file2 etc"
-func DefineInputs(seed *objects.Seed, inputs []string) ([]string, float64, map[string]string, error) {
+// The files of multiple inputs are staged in the returned Staging, which the caller
+// must clean up. filter selects the files of directories given for multiple inputs.
+// 	float64: the total size of the input files in MiB, including the files in directories
+func DefineInputs(seed *objects.Seed, inputs []string, filter InputFilter) ([]string, float64, *Staging, error) {
+	return defineInputs(seed, inputs, filter, true)
+}
+
+//defineInputs is DefineInputs, only creating the staging directory multiple file
+// inputs are linked into when create is true
+func defineInputs(seed *objects.Seed, inputs []string, filter InputFilter, create bool) ([]string, float64, *Staging, error) {
 	// Validate inputs given vs. inputs defined in manifest
 
 	var mountArgs []string
 	var sizeMiB float64
 
-	inMap := inputMap(inputs, true)
+	inMap := fileInputMap(seed, inputs)
+	staging := NewStaging(create)
+	staging.Filter = filter
 
 	// Valid by default
 	valid := true
 	var keys []string
-	var unrequired []string
-	var tempDirectories map[string]string
-	tempDirectories = make(map[string]string)
+	multiple := map[string]bool{}
 	for _, f := range seed.Job.Interface.Inputs.Files {
 		normalName := util.GetNormalizedVariable(f.Name)
-		if f.Multiple {
-			tempDir := "temp-" + time.Now().Format(time.RFC3339)
-			tempDir = strings.Replace(tempDir, ":", "_", -1)
-			os.Mkdir(tempDir, os.ModePerm)
-			tempDirectories[normalName] = tempDir
-			mountArgs = append(mountArgs, "-v")
-			mountArgs = append(mountArgs, util.GetFullPath(tempDir, "")+":/"+tempDir)
-			mountArgs = append(mountArgs, "-e")
-			mountArgs = append(mountArgs, normalName+"=/"+tempDir)
-
-		}
+		multiple[normalName] = f.Multiple
 		if f.Required == false {
-			unrequired = append(unrequired, normalName)
 			continue
 		}
 		keys = append(keys, normalName)
@@ -273,75 +651,46 @@ func DefineInputs(seed *objects.Seed, inputs []string) ([]string, float64, map[s
 		for _, n := range keys {
 			buffer.WriteString("  " + n + "\n")
 		}
-		return nil, 0.0, tempDirectories, errors.New(buffer.String())
+		return nil, 0.0, staging, errors.New(buffer.String())
+	}
+
+	var names []string
+	for key := range inMap {
+		names = append(names, key)
 	}
+	sort.Strings(names)
+	for _, key := range names {
+		if multiple[key] {
+			// the files of a multiple input are staged in a directory mounted into the container
+			in, err := staging.Stage(key, inMap[key])
+			if err != nil {
+				return nil, 0.0, staging, err
+			}
+			sizeMiB += (1.0 * float64(in.Size)) / (1024.0 * 1024.0)
+			mountArgs = append(mountArgs, in.Args()...)
+			continue
+		}
 
-	for key, val := range inMap {
 		// Expand input VALUE
-		val = util.GetFullPath(val, "")
+		val := util.GetFullPath(inMap[key][0], "")
 
 		//get total size of input files in MiB
-		info, err := os.Stat(val)
+		_, err := os.Stat(val)
 		if os.IsNotExist(err) {
 			msg := fmt.Sprintf("ERROR: Input file %s not found\n", val)
-			return nil, 0.0, tempDirectories, errors.New(msg)
+			return nil, 0.0, staging, errors.New(msg)
 		}
-		sizeMiB += (1.0 * float64(info.Size())) / (1024.0 * 1024.0) //fileinfo's Size() returns bytes, convert to MiB
+		sizeMiB += (1.0 * float64(pathSize(val))) / (1024.0 * 1024.0) //sizes are in bytes, convert to MiB
 
-		// Replace key if found in args strings
-		// Handle replacing KEY or ${KEY} or $KEY
-		value := val
-		if directory, ok := tempDirectories[key]; ok {
-			value = "/" + directory //replace with the temp directory located at the root if multiple files
-		}
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-			"${"+key+"}", value, -1)
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command, "$"+key,
-			value, -1)
-
-		var errMsg bytes.Buffer
-		for _, k := range seed.Job.Interface.Inputs.Files {
-			normalName := util.GetNormalizedVariable(k.Name)
-			if normalName == key {
-				if k.Multiple {
-					if info.IsDir() {
-						// Can't hardlink to a directory
-						// Can't symlink to an existing directory, so remove it first
-						os.Remove(tempDirectories[key])
-						if err = os.Symlink(val, tempDirectories[key]); err != nil {
-							errMsg.WriteString("ERROR: Permissions error linking to input files for input " + key + ".\n" + err.Error() + "\n")
-						}
-					} else {
-						//directory has already been added to mount args, just link file into that directory
-						if err = os.Link(val, filepath.Join(tempDirectories[key], info.Name())); err != nil && os.IsPermission(err) {
-							errMsg.WriteString("ERROR: Permissions error linking to input files for input " + key + ".\n" + err.Error() + "\n")
-						}
-					}
-
-				} else {
-					mountArgs = append(mountArgs, "-v")
-					mountArgs = append(mountArgs, val+":"+val)
-					mountArgs = append(mountArgs, "-e")
-					mountArgs = append(mountArgs, key+"="+val)
-				}
-			}
-		}
-		if errMsg.String() != "" {
-			return nil, 0.0, tempDirectories, errors.New(errMsg.String())
+		if _, ok := multiple[key]; ok {
+			mountArgs = append(mountArgs, "-v")
+			mountArgs = append(mountArgs, val+":"+val)
+			mountArgs = append(mountArgs, "-e")
+			mountArgs = append(mountArgs, key+"="+val)
 		}
 	}
 
-	//remove unspecified unrequired inputs from cmd string
-	for _, k := range unrequired {
-		key := k
-		value := ""
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-			"${"+key+"}", value, -1)
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command, "$"+key,
-			value, -1)
-	}
-
-	return mountArgs, sizeMiB, tempDirectories, nil
+	return mountArgs, sizeMiB, staging, nil
 }
 
 //DefineInputJson passes input json values from the 'run' command
@@ -359,11 +708,9 @@ func DefineInputJson(seed *objects.Seed, inputs []string) ([]string, error) {
 	// Valid by default
 	valid := true
 	var keys []string
-	var unrequired []string
 	for _, f := range seed.Job.Interface.Inputs.Json {
 		normalName := util.GetNormalizedVariable(f.Name)
 		if f.Required == false {
-			unrequired = append(unrequired, normalName)
 			continue
 		}
 		keys = append(keys, normalName)
@@ -395,35 +742,23 @@ func DefineInputJson(seed *objects.Seed, inputs []string) ([]string, error) {
 					value = val
 				}
 
-				// Replace key if found in args strings
-				// Handle replacing KEY or ${KEY} or $KEY
-				seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-					"${"+key+"}", value, -1)
-				seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command, "$"+key,
-					value, -1)
-
 				envArgs = append(envArgs, "-e")
 				envArgs = append(envArgs, key+"="+value)
 			}
 		}
 	}
 
-	//remove unspecified unrequired inputs from cmd string
-	for _, k := range unrequired {
-		key := k
-		value := ""
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-			"${"+key+"}", value, -1)
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command, "$"+key,
-			value, -1)
-	}
-
 	return envArgs, nil
 }
 
-//SetOutputDir replaces the OUTPUT_DIR argument with the given output directory.
-// Returns output directory string
+//SetOutputDir resolves the job output directory, creating it if it doesn't exist or a
+// time-stamped sub-directory of it if it isn't empty. Returns output directory string
 func SetOutputDir(imageName string, seed *objects.Seed, outputDir string) string {
+	return resolveOutputDir(imageName, seed, outputDir, true)
+}
+
+//resolveOutputDir is SetOutputDir, only creating the output directory when create is true
+func resolveOutputDir(imageName string, seed *objects.Seed, outputDir string, create bool) string {
 	// #37: if -o is not specified, auto create a time-stamped subdirectory with the name of the form:
 	//		imagename-iso8601timestamp
 	if outputDir == "" {
@@ -435,6 +770,9 @@ func SetOutputDir(imageName string, seed *objects.Seed, outputDir string) string
 
 	// Check if outputDir exists. Create if not
 	if _, err := os.Stat(outdir); os.IsNotExist(err) {
+		if !create {
+			return outdir
+		}
 		// Create the directory
 		// Didn't find the specified directory
 		util.PrintUtil("INFO: %s not found; creating directory...\n",
@@ -450,7 +788,9 @@ func SetOutputDir(imageName string, seed *objects.Seed, outputDir string) string
 	}
 	defer f.Close()
 	_, err = f.Readdirnames(1)
-	if err != io.EOF {
+	if err != io.EOF && !create {
+		return filepath.Join(outdir, time.Now().Format("20060102_150405"))
+	} else if err != io.EOF {
 		// Directory is not empty
 		t := time.Now().Format("20060102_150405")
 		util.PrintUtil(
@@ -460,10 +800,6 @@ func SetOutputDir(imageName string, seed *objects.Seed, outputDir string) string
 		os.Mkdir(outdir, os.ModePerm)
 	}
 
-	seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-		"$OUTPUT_DIR", outdir, -1)
-	seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-		"${OUTPUT_DIR}", outdir, -1)
 	return outdir
 }
 
@@ -516,7 +852,10 @@ func DefineMounts(seed *objects.Seed, inputs []string) ([]string, error) {
 //DefineSettings defines any seed specified docker settings.
 // Return []string of docker command arguments in form of:
 //	"-e setting1=val1 -e setting2=val2 etc"
-func DefineSettings(seed *objects.Seed, inputs []string) ([]string, error) {
+// Settings marked secret in the manifest are not included in the docker command
+// arguments. They are returned separately in the form "setting3=val3" so they can
+// be passed to the container without being exposed on the command line.
+func DefineSettings(seed *objects.Seed, inputs []string) ([]string, []string, error) {
 	inMap := inputMap(inputs, true)
 
 	// Valid by default
@@ -540,67 +879,88 @@ func DefineSettings(seed *objects.Seed, inputs []string) ([]string, error) {
 			buffer.WriteString("  " + n + "\n")
 		}
 		buffer.WriteString("\n")
-		return nil, errors.New(buffer.String())
+		return nil, nil, errors.New(buffer.String())
+	}
+
+	referenced := map[string]bool{}
+	variables, _ := CommandVariables(seed.Job.Interface.Command)
+	for _, name := range variables {
+		referenced[name] = true
 	}
 
 	var settings []string
-	for _, key := range keys {
-		// Replace key if found in args strings
-		// Handle replacing KEY or ${KEY} or $KEY
+	var secrets []string
+	for i, key := range keys {
 		value := inMap[key]
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command,
-			"${"+key+"}", value, -1)
-		seed.Job.Interface.Command = strings.Replace(seed.Job.Interface.Command, "$"+key,
-			value, -1)
+		secret := seed.Job.Interface.Settings[i].Secret
+		if secret && referenced[key] {
+			util.PrintUtil("WARNING: Secret setting %s is used in the job command; its value will be visible in the process list.\n", key)
+		}
 
+		if secret {
+			secrets = append(secrets, key+"="+value)
+			continue
+		}
 		settings = append(settings, "-e")
 		settings = append(settings, key+"="+value)
 	}
 
-	return settings, nil
+	return settings, secrets, nil
 }
 
 //DefineResources defines any seed specified docker resource requirements
 //based on the seed spec and the size of the input in MiB
+// overrides are applied to the computed requirements, see parseResourceOverrides
+// cpuset pins the container to the first cpus CPUs instead of limiting its CPU time
 // returns array of arguments to pass to docker to restrict/specify the resources required
 // returns the total disk space requirement to be checked when validating output
-func DefineResources(seed *objects.Seed, inputSizeMiB float64) ([]string, float64, error) {
+func DefineResources(seed *objects.Seed, inputSizeMiB float64, overrides []string, cpuset bool) ([]string, float64, error) {
 	var resources []string
 	var disk float64
 
+	overrideMap, err := parseResourceOverrides(overrides)
+	if err != nil {
+		return nil, 0.0, err
+	}
+
 	for _, s := range seed.Job.Resources.Scalar {
-		value := fmt.Sprintf("%f", s.Value)
+		//resourceRequirement = inputVolume * inputMultiplier + constantValue
+		amount := (s.InputMultiplier * inputSizeMiB) + s.Value
+		amount = applyResourceOverrides(s.Name, amount, overrideMap)
+
+		value := fmt.Sprintf("%f", amount)
+		if s.Name == "cpus" {
+			cpus := math.Max(amount, 0.01) //docker cpu limit must be >= 0.01
+			if available := float64(runtime.NumCPU()); cpus > available {
+				util.PrintUtil("WARNING: %v cpus requested but only %v are available. Limiting job to %v cpus.\n",
+					cpus, available, available)
+				cpus = available
+			}
+			if cpuset {
+				resources = append(resources, fmt.Sprintf("--cpuset-cpus=%s", cpusetString(cpus)))
+			} else {
+				resources = append(resources, fmt.Sprintf("--cpus=%s", strconv.FormatFloat(cpus, 'f', -1, 64)))
+			}
+			value = fmt.Sprintf("%f", cpus)
+		}
 		if s.Name == "mem" {
-			//resourceRequirement = inputVolume * inputMultiplier + constantValue
-			mem := (s.InputMultiplier * inputSizeMiB) + s.Value
-			mem = math.Max(mem, 4.0)        //docker memory requirement must be > 4MiB
+			mem := math.Max(amount, 4.0)    //docker memory requirement must be > 4MiB
 			intMem := int64(math.Ceil(mem)) //docker expects integer, get the ceiling of the specified value and convert
 			resources = append(resources, "-m")
 			resources = append(resources, fmt.Sprintf("%dm", intMem))
 			value = fmt.Sprintf("%d", intMem)
 		}
 		if s.Name == "disk" {
-			//resourceRequirement = inputVolume * inputMultiplier + constantValue
-			disk = (s.InputMultiplier * inputSizeMiB) + s.Value
-			value = fmt.Sprintf("%f", disk)
+			disk = amount
 		}
 		if s.Name == "sharedMem" {
-			//resourceRequirement = inputVolume * inputMultiplier + constantValue
-			mem := (s.InputMultiplier * inputSizeMiB) + s.Value
-			intMem := int64(math.Ceil(mem)) //docker expects integer, get the ceiling of the specified value and convert
+			intMem := int64(math.Ceil(amount)) //docker expects integer, get the ceiling of the specified value and convert
 			resources = append(resources, fmt.Sprintf("--shm-size=%dm", intMem))
 			value = fmt.Sprintf("%d", intMem)
 		}
 		if s.Name == "gpus" {
-			var gpustring string
-			for g := 0; g < int(s.Value); g++ {
-				gpustring += strconv.Itoa(g) + ","
-			}
-			gpustring = strings.Trim(gpustring, ",")
-			resources = append(resources, "--runtime=nvidia")
-			resources = append(resources, "-e")
-			resources = append(resources, fmt.Sprintf("NVIDIA_VISIBLE_DEVICES=%s", gpustring))
-			value = fmt.Sprintf("%d", int(s.Value))
+			resources = append(resources, cliutil.CurrentRuntime().GPUArgs(int(amount))...)
+			value = fmt.Sprintf("%d", int(amount))
 		}
 
 		envVar := util.GetNormalizedVariable("ALLOCATED_" + s.Name)
@@ -611,26 +971,109 @@ func DefineResources(seed *objects.Seed, inputSizeMiB float64) ([]string, float6
 	return resources, disk, nil
 }
 
+//cpusetString returns the docker --cpuset-cpus list pinning a job to the first
+// ceil(cpus) CPUs, i.e. 0 or 0-3
+func cpusetString(cpus float64) string {
+	last := int(math.Ceil(cpus)) - 1
+	if last <= 0 {
+		return "0"
+	}
+	return fmt.Sprintf("0-%d", last)
+}
+
+//resourceOverride is an operator supplied adjustment to a computed scalar resource
+type resourceOverride struct {
+	op    string
+	value float64
+}
+
+//parseResourceOverrides parses -resources arguments into a map of lower case
+// resource name to overrides. Overrides take one of the following forms:
+//	NAME=VALUE		use VALUE instead of the computed requirement
+//	NAME=min:VALUE	raise the requirement to at least VALUE
+//	NAME=max:VALUE	clamp the requirement to at most VALUE
+//	NAME=xFACTOR	scale the requirement by FACTOR
+// The name "all" applies the override to every scalar resource.
+func parseResourceOverrides(overrides []string) (map[string][]resourceOverride, error) {
+	overrideMap := make(map[string][]resourceOverride)
+	for _, o := range overrides {
+		if o == "" {
+			//skip empty strings
+			continue
+		}
+		x := strings.SplitN(o, "=", 2)
+		if len(x) != 2 || x[0] == "" {
+			return nil, fmt.Errorf("ERROR: Resource override %s should be specified in NAME=VALUE format.\n", o)
+		}
+
+		op := "="
+		valStr := x[1]
+		if strings.HasPrefix(valStr, "min:") || strings.HasPrefix(valStr, "max:") {
+			op = valStr[:3]
+			valStr = valStr[4:]
+		} else if strings.HasPrefix(valStr, "x") {
+			op = "x"
+			valStr = valStr[1:]
+		}
+
+		value, err := strconv.ParseFloat(valStr, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("ERROR: Invalid value for resource override %s. Expected NAME=VALUE, NAME=min:VALUE, NAME=max:VALUE or NAME=xFACTOR.\n", o)
+		}
+
+		name := strings.ToLower(x[0])
+		overrideMap[name] = append(overrideMap[name], resourceOverride{op, value})
+	}
+	return overrideMap, nil
+}
+
+//applyResourceOverrides applies any overrides for all resources followed by any
+// overrides for the named resource to the given amount
+func applyResourceOverrides(name string, amount float64, overrideMap map[string][]resourceOverride) float64 {
+	overrides := append([]resourceOverride{}, overrideMap["all"]...)
+	overrides = append(overrides, overrideMap[strings.ToLower(name)]...)
+	for _, o := range overrides {
+		switch o.op {
+		case "=":
+			amount = o.value
+		case "min":
+			amount = math.Max(amount, o.value)
+		case "max":
+			amount = math.Min(amount, o.value)
+		case "x":
+			amount = amount * o.value
+		}
+	}
+	return amount
+}
+
 //CheckRunOutput validates the output of the docker run command. Output data is
-// validated as defined in the seed.Job.Interface.Outputs.
-func CheckRunOutput(seed *objects.Seed, outDir, metadataSchema string, diskLimit float64) {
+// validated as defined in the seed.Job.Interface.Outputs. Problems are printed as
+// they are found and returned in the validation result. Also returns the outputs
+// found: the files matching each output with their side-car metadata, and the values
+// in seed.outputs.json that have the type the manifest defines.
+func CheckRunOutput(seed *objects.Seed, outDir, metadataSchema string, diskLimit float64) (*OutputValidation, *RunOutputs) {
+	result := &OutputValidation{DiskLimit: diskLimit}
+	outputs := &RunOutputs{Files: map[string][]OutputFile{}, JSON: map[string]interface{}{}}
+
+	var dirSize int64
+	readSize := func(path string, file os.FileInfo, err error) error {
+		if err == nil && !file.IsDir() {
+			dirSize += file.Size()
+		}
+
+		return nil
+	}
+	filepath.Walk(outDir, readSize)
+	result.DiskUsage = float64(dirSize) / (1024.0 * 1024.0)
+
 	// Validate any Outputs.Files
 	if seed.Job.Interface.Outputs.Files != nil {
 		util.PrintUtil("INFO: Validating output files found under %s...\n",
 			outDir)
 
-		var dirSize int64
-		readSize := func(path string, file os.FileInfo, err error) error {
-			if !file.IsDir() {
-				dirSize += file.Size()
-			}
-
-			return nil
-		}
-		filepath.Walk(outDir, readSize)
-		sizeMB := float64(dirSize) / (1024.0 * 1024.0)
-		if diskLimit > 0 && sizeMB > diskLimit {
-			util.PrintUtil("ERROR: Output directory exceeds disk space limit (%f MiB vs. %f MiB)\n", sizeMB, diskLimit)
+		if result.DiskExceeded() {
+			util.PrintUtil("ERROR: Output directory exceeds disk space limit (%f MiB vs. %f MiB)\n", result.DiskUsage, diskLimit)
 		}
 
 		// For each defined Outputs file:
@@ -638,43 +1081,75 @@ func CheckRunOutput(seed *objects.Seed, outDir, metadataSchema string, diskLimit
 		// 	#2 Check file names match output pattern
 		//  #3 Check number of files (if defined)
 		for _, f := range seed.Job.Interface.Outputs.Files {
+			fileResult := OutputFileResult{Name: f.Name, Pattern: f.Pattern, Required: f.Required,
+				Multiple: f.Multiple, Matches: []string{}}
+
 			// find all pattern matches in OUTPUT_DIR
 			matches, _ := filepath.Glob(path.Join(outDir, f.Pattern))
 
 			// Check media type of matches
-			count := 0
 			var matchList []string
 			for _, match := range matches {
 				ext := filepath.Ext(match)
 				mType := mime.TypeByExtension(ext)
-				if strings.Contains(mType, f.MediaType) ||
-					strings.Contains(f.MediaType, mType) {
-					count++
-					matchList = append(matchList, "\t"+match+"\n")
-					metadata := match + ".metadata.json"
-					if _, err := os.Stat(metadata); err == nil {
-						schema := metadataSchema
-						if schema != "" {
-							schema = util.GetFullPath(schema, "")
-						}
-						err := ValidateSeedFile(false, schema, seed.SeedVersion, metadata, common_const.SchemaMetadata)
-						if err != nil {
-							util.PrintUtil("ERROR: Side-car metadata file %s validation error: %s", metadata, err.Error())
+				if !strings.Contains(mType, f.MediaType) &&
+					!strings.Contains(f.MediaType, mType) {
+					fileResult.MediaTypeMismatches = append(fileResult.MediaTypeMismatches,
+						MediaTypeMismatch{File: match, MediaType: mType, Expected: f.MediaType})
+					continue
+				}
+
+				fileResult.Matches = append(fileResult.Matches, match)
+				matchList = append(matchList, "\t"+match+"\n")
+				outputFile := OutputFile{Path: match}
+				metadata := match + ".metadata.json"
+				if _, err := os.Stat(metadata); err == nil {
+					outputFile.MetadataFile = metadata
+					outputFile.Metadata = readMetadata(metadata)
+					schema := metadataSchema
+					if schema != "" {
+						schema = util.GetFullPath(schema, "")
+					}
+					err := ValidateSeedFile(false, schema, seed.SeedVersion, metadata, common_const.SchemaMetadata)
+					if err != nil {
+						util.PrintUtil("ERROR: Side-car metadata file %s validation error: %s\n", metadata, err.Error())
+						fileResult.MetadataErrors = append(fileResult.MetadataErrors,
+							MetadataError{File: metadata, Error: err.Error()})
+					}
+
+					// the schema can't check that the geometry and timestamps make sense
+					problems, _ := CheckGeoJSON(outputFile.Metadata)
+					for _, p := range problems {
+						if p.Warning {
+							util.PrintUtil("WARNING: Side-car metadata file %s: %s\n", metadata, p)
+							fileResult.MetadataWarnings = append(fileResult.MetadataWarnings,
+								MetadataError{File: metadata, Error: p.String()})
+						} else {
+							util.PrintUtil("ERROR: Side-car metadata file %s validation error: %s\n", metadata, p)
+							fileResult.MetadataErrors = append(fileResult.MetadataErrors,
+								MetadataError{File: metadata, Error: p.String()})
 						}
 					}
 				}
+				outputs.Files[f.Name] = append(outputs.Files[f.Name], outputFile)
+			}
+
+			for _, m := range fileResult.MediaTypeMismatches {
+				util.PrintUtil("WARNING: File %s matches output %v but has media type %s, expected %s.\n",
+					m.File, f.Name, m.MediaType, f.MediaType)
 			}
 
 			expected := 1
-			errStr := "ERROR: Required file expected for output %v, %v found.\n"
+			errStr := "Required file expected for output %v, %v found."
 			if f.Multiple == true {
 				expected = 2
-				errStr = "ERROR: Multiple required files expected for output %v, %v found.\n"
+				errStr = "Multiple required files expected for output %v, %v found."
 			}
 
 			// Validate that any required fields are present
 			if f.Required && len(matchList) < expected {
-				util.PrintUtil(errStr, f.Name, strconv.Itoa(len(matchList)))
+				fileResult.Error = fmt.Sprintf(errStr, f.Name, strconv.Itoa(len(matchList)))
+				util.PrintUtil("ERROR: %s\n", fileResult.Error)
 			} else if !f.Multiple && len(matchList) > 1 {
 				util.PrintUtil("WARNING: Multiple files found for single output %v, %v found.\n",
 					f.Name, strconv.Itoa(len(matchList)))
@@ -689,72 +1164,67 @@ func CheckRunOutput(seed *objects.Seed, outDir, metadataSchema string, diskLimit
 					util.PrintUtil(s)
 				}
 			}
+			result.Files = append(result.Files, fileResult)
 		}
+	} else if result.DiskExceeded() {
+		util.PrintUtil("ERROR: Output directory exceeds disk space limit (%f MiB vs. %f MiB)\n", result.DiskUsage, diskLimit)
 	}
 
 	// Validate any defined Outputs.Json
 	// Look for ResultsFileManifestName.json in the root of the OUTPUT_DIR
 	// and then validate any keys identified in Outputs exist
 	if seed.Job.Interface.Outputs.JSON != nil {
+		jsonFail := func(key, format string, args ...interface{}) {
+			msg := fmt.Sprintf(format, args...)
+			util.PrintUtil("ERROR: %s\n", msg)
+			result.JSON = append(result.JSON, OutputJSONError{Key: key, Error: msg})
+		}
+
 		util.PrintUtil("INFO: Validating %s...\n",
 			filepath.Join(outDir, constants.ResultsFileManifestName))
 		// look for results manifest
 		manfile := filepath.Join(outDir, constants.ResultsFileManifestName)
 		if _, err := os.Stat(manfile); os.IsNotExist(err) {
-			util.PrintUtil("ERROR: %s specified but cannot be found. %s\n Exiting testrunner.\n",
+			jsonFail("", "%s specified but cannot be found. %s",
 				constants.ResultsFileManifestName, err.Error())
-			return
+			return result, outputs
 		}
 
 		bites, err := ioutil.ReadFile(filepath.Join(outDir,
 			constants.ResultsFileManifestName))
 		if err != nil {
-			util.PrintUtil("ERROR: Error reading %s.%s\n",
+			jsonFail("", "Error reading %s. %s",
 				constants.ResultsFileManifestName, err.Error())
-			return
+			return result, outputs
 		}
 
 		documentLoader := gojsonschema.NewStringLoader(string(bites))
 		_, err = documentLoader.LoadJSON()
 		if err != nil {
-			util.PrintUtil("ERROR: Error loading results manifest file: %s. %s\n Exiting testrunner.\n",
+			jsonFail("", "Error loading results manifest file: %s. %s",
 				constants.ResultsFileManifestName, err.Error())
-			return
+			return result, outputs
 		}
 
-		schemaFmt := "{ \"type\": \"object\", \"properties\": { %s }, \"required\": [ %s ] }"
-		schema := ""
-		required := ""
-
-		// Loop through defined name/key values to extract from seed.outputs.json
-		for _, jsonStr := range seed.Job.Interface.Outputs.JSON {
-			key := jsonStr.Name
-			if jsonStr.Key != "" {
-				key = jsonStr.Key
-			}
-
-			schema += fmt.Sprintf("\"%s\": { \"type\": \"%s\" },", key, jsonStr.Type)
-
-			if jsonStr.Required {
-				required += fmt.Sprintf("\"%s\",", key)
+		// the schema is built as a value so keys are escaped however they're written
+		properties := map[string]interface{}{}
+		var required []string
+		for _, o := range seed.Job.Interface.Outputs.JSON {
+			properties[outputKey(o)] = map[string]interface{}{"type": o.Type}
+			if o.Required {
+				required = append(required, outputKey(o))
 			}
 		}
-		//remove trailing commas
-		if len(schema) > 0 {
-			schema = schema[:len(schema)-1]
-		}
+		schema := map[string]interface{}{"type": "object", "properties": properties}
 		if len(required) > 0 {
-			required = required[:len(required)-1]
+			schema["required"] = required
 		}
 
-		schema = fmt.Sprintf(schemaFmt, schema, required)
-
-		schemaLoader := gojsonschema.NewStringLoader(schema)
+		schemaLoader := gojsonschema.NewGoLoader(schema)
 		schemaResult, err := gojsonschema.Validate(schemaLoader, documentLoader)
 		if err != nil {
-			util.PrintUtil("ERROR: Error running validator: %s\n Exiting testrunner.\n",
-				err.Error())
-			return
+			jsonFail("", "Error running validator: %s", err.Error())
+			return result, outputs
 		}
 
 		if len(schemaResult.Errors()) == 0 {
@@ -762,9 +1232,21 @@ func CheckRunOutput(seed *objects.Seed, outDir, metadataSchema string, diskLimit
 		}
 
 		for _, desc := range schemaResult.Errors() {
-			util.PrintUtil("ERROR: %s is invalid: - %s\n", constants.ResultsFileManifestName, desc)
+			// missing required keys are reported against the root object
+			key := desc.Field()
+			if property, ok := desc.Details()["property"].(string); ok {
+				key = property
+			}
+			jsonFail(key, "%s is invalid: - %s", constants.ResultsFileManifestName, desc)
+		}
+
+		// values of the wrong type were reported above and are left out of the outputs
+		for name, value := range outputValues(seed.Job.Interface.Outputs.JSON, bites) {
+			outputs.JSON[name] = value
 		}
 	}
+
+	return result, outputs
 }
 
 //PrintRunUsage prints the seed run usage arguments, then exits the program
@@ -778,10 +1260,16 @@ func PrintRunUsage() {
 		constants.ShortImgNameFlag, constants.ImgNameFlag)
 	util.PrintUtil("  -%s   -%s\tManifest file to use if an image name is not specified (default is seed.manifest.json within the current directory).\n",
 		constants.ShortManifestFlag, constants.ManifestFlag)
-	util.PrintUtil("  -%s   -%s \t\tSpecifies the key/value input data values of the seed spec in the format INPUT_FILE_KEY=INPUT_FILE_VALUE\n",
-		constants.ShortInputsFlag, constants.InputsFlag)
+	util.PrintUtil("  -%s   -%s \t\tYAML or JSON job spec file giving the image or manifest, inputs, json, settings, mounts, output directory, schema, repetitions and resources. Flags override values in the file\n",
+		constants.ShortJobSpecFlag, constants.JobSpecFlag)
+	util.PrintUtil("  -%s   -%s \t\tSpecifies the key/value input data values of the seed spec in the format INPUT_FILE_KEY=INPUT_FILE_VALUE\n"+
+		"\t\t\tThe -%s, -%s, -%s, -%s and -%s flags may be repeated; escape a comma followed by KEY= in a value as \\,\n",
+		constants.ShortInputsFlag, constants.InputsFlag, constants.ShortInputsFlag, constants.ShortJsonFlag,
+		constants.ShortSettingFlag, constants.ShortMountFlag, constants.ResourcesFlag)
 	util.PrintUtil("  -%s   -%s \tSpecifies the key/value setting values of the seed spec in the format SETTING_KEY=VALUE\n",
 		constants.ShortSettingFlag, constants.SettingFlag)
+	util.PrintUtil("  -%s \t\tFile of SETTING_KEY=VALUE lines for secret settings. Secret settings not given are read from the environment\n",
+		constants.SecretsFlag)
 	util.PrintUtil("  -%s   -%s \t\tSpecifies the key/value mount values of the seed spec in the format MOUNT_KEY=HOST_PATH\n",
 		constants.ShortMountFlag, constants.MountFlag)
 	util.PrintUtil("  -%s   -%s \t\tJob Output Directory Location\n",
@@ -794,6 +1282,30 @@ func PrintRunUsage() {
 		constants.ShortRepeatFlag, constants.RepeatFlag)
 	util.PrintUtil("  -%s   -%s \t\tExternal Seed metadata schema file; Overrides built in schema to validate side-car metadata files\n",
 		constants.ShortSchemaFlag, constants.SchemaFlag)
+	util.PrintUtil("  -%s   -%s \tJob timeout in seconds; overrides job.timeout from the seed manifest\n",
+		constants.ShortTimeoutFlag, constants.TimeoutFlag)
+	util.PrintUtil("  -%s \t\tOverrides a scalar resource in the format NAME=VALUE, NAME=min:VALUE, NAME=max:VALUE or NAME=xFACTOR (use NAME 'all' for every resource)\n",
+		constants.ResourcesFlag)
+	util.PrintUtil("  -%s  \t\tPins the job to its cpus resource with docker --cpuset-cpus instead of limiting with --cpus\n",
+		constants.CPUSetFlag)
+	util.PrintUtil("  -%s \tExits with a non-zero status if the job's output fails validation\n",
+		constants.StrictOutputsFlag)
+	util.PrintUtil("  -%s \tWhether to warn (default), fail or skip the check when an input file's media type isn't in the seed manifest\n",
+		constants.MediaTypesFlag)
+	util.PrintUtil("  -%s \tComma separated patterns of the files to stage from directories given for multiple file inputs; the directories are expanded recursively\n",
+		constants.InputIncludeFlag)
+	util.PrintUtil("  -%s \tComma separated patterns of the files and subdirectories to leave out of directories given for multiple file inputs\n",
+		constants.InputExcludeFlag)
+	util.PrintUtil("  -%s \t\tPrints the docker run command, expanded job command and resource allocations without running the job\n",
+		constants.DryRunFlag)
+	util.PrintUtil("  -%s \tFormat of the result: text (default) or json, printing the run result with its outputs, or the dry run, to stdout\n",
+		constants.OutputFormatFlag)
+	util.PrintUtil("  -%s \t\tRuns the job without restoring or storing its output in the result cache enabled by %s\n",
+		constants.NoCacheFlag, constants.CacheDirEnv)
+	util.PrintUtil("  -%s \tPrefixes each line of %s and %s in the output directory with the time it was written\n",
+		constants.LogTimestampsFlag, constants.StdoutLogFile, constants.StderrLogFile)
+	util.PrintUtil("  -%s \t\tStarts an interactive shell in the prepared job container instead of running the job; the job command is saved to %s/%s\n",
+		constants.DebugFlag, constants.DebugScriptDir, constants.DebugScript)
 	return
 }
 
@@ -822,6 +1334,28 @@ func inputMap(inputs []string, normalize bool) map[string]string {
 	return inMap
 }
 
+//fileInputMap is inputMap for the file inputs of seed, mapping each key to the list of
+// values given for it. Values given more than once for a multiple input accumulate in
+// order; other inputs keep only the last value given.
+func fileInputMap(seed *objects.Seed, inputs []string) map[string][]string {
+	multiple := map[string]bool{}
+	for _, f := range seed.Job.Interface.Inputs.Files {
+		multiple[util.GetNormalizedVariable(f.Name)] = f.Multiple
+	}
+
+	inMap := make(map[string][]string)
+	for _, in := range inputs {
+		for key, value := range inputMap([]string{in}, true) {
+			if multiple[key] {
+				inMap[key] = append(inMap[key], value)
+			} else {
+				inMap[key] = []string{value}
+			}
+		}
+	}
+	return inMap
+}
+
 func ReadJsonFile(filename string) (string, error) {
 	filebytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -1,10 +1,18 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ngageoint/seed-cli/constants"
 	"github.com/ngageoint/seed-common/objects"
 	"github.com/ngageoint/seed-common/util"
 )
@@ -56,7 +64,7 @@ func TestDockerRun(t *testing.T) {
 		version := "1.0.0"
 		DockerBuild(c.directory, version, "", "", ".", ".", "", false)
 		_, err := DockerRun(c.imageName, c.manifest, outputDir, metadataSchema,
-			c.inputs, c.json, c.settings, c.mounts, true, true)
+			c.inputs, c.json, c.settings, c.mounts, true, RunOptions{})
 		success := err == nil
 		if success != c.expected {
 			t.Errorf("DockerRun(%q, %q, %q, %q, %q, %q, %q) == %v, expected %v", c.imageName, c.manifest, outputDir, metadataSchema, c.inputs, c.settings, c.mounts, err, nil)
@@ -75,7 +83,7 @@ func TestDefineInputs(t *testing.T) {
 		inputs           []string
 		expectedVol      string
 		expectedSize     string
-		expectedTempDir  string
+		expectedStaged   string
 		expected         bool
 		expectedErrorMsg string
 	}{
@@ -85,9 +93,9 @@ func TestDefineInputs(t *testing.T) {
 			"map[]", true, ""},
 		{"../examples/extractor/seed.manifest.json",
 			[]string{"ZIP=../testdata/seed-scale.zip", "MULTIPLE=../testdata/"},
-			"[-v $MULTIPLE$:/$MULTIPLETEMP$ -e MULTIPLE=/$MULTIPLETEMP$ -v $ZIP$:$ZIP$ -e ZIP=$ZIP$]",
-			"0.1",
-			"map[MULTIPLE:$MULTIPLETEMP$]", true, ""}, // "ERROR: Permissions error linking to input files for input MULTIPLE."},
+			"[-v $MULTIPLE$:/seed-inputs/MULTIPLE -e MULTIPLE=/seed-inputs/MULTIPLE -v $ZIP$:$ZIP$ -e ZIP=$ZIP$]",
+			"0.2",
+			"map[MULTIPLE:/seed-inputs/MULTIPLE]", true, ""},
 		{"../testdata/complete/seed.manifest.json",
 			[]string{"inPut-File=../testdata/seed-scale.zip"},
 			"[-v $inPut-File$:$inPut-File$ -e INPUT_FILE=$inPut-File$]", "0.1",
@@ -105,30 +113,19 @@ func TestDefineInputs(t *testing.T) {
 	for _, c := range cases {
 		seedFileName := util.GetFullPath(c.seedFileName, "")
 		seed := objects.SeedFromManifestFile(seedFileName)
-		volumes, size, tempDir, err := DefineInputs(&seed, c.inputs)
+		volumes, size, staging, err := DefineInputs(&seed, c.inputs, InputFilter{})
+		defer staging.Cleanup()
 
 		if c.expected != (err == nil) {
 			t.Errorf("DefineInputs(%q, %q) == %v, expected %v", seedFileName, c.inputs, err, nil)
 		}
 
 		expectedVol := c.expectedVol
-		expectedTempDir := c.expectedTempDir
 		for _, f := range c.inputs {
 			x := strings.Split(f, "=")
-			tempDir, ok := tempDir[x[0]]
-			if ok {
-				defer util.RemoveAllFiles(tempDir)
-				tempVarStr := fmt.Sprintf("$%sTEMP$", x[0])
-				expectedVol = strings.Replace(expectedVol, tempVarStr, tempDir, -1)
-				path := util.GetFullPath(tempDir, "")
-				replaceNameStr := fmt.Sprintf("$%s$", x[0])
-				expectedVol = strings.Replace(expectedVol, replaceNameStr, path, -1)
-				expectedTempDir = strings.Replace(expectedTempDir, tempVarStr, tempDir, -1)
-			} else {
-				path := util.GetFullPath(x[1], "")
-				replaceNameStr := fmt.Sprintf("$%s$", x[0])
-				expectedVol = strings.Replace(expectedVol, replaceNameStr, path, -1)
-			}
+			path := util.GetFullPath(x[1], "")
+			replaceNameStr := fmt.Sprintf("$%s$", x[0])
+			expectedVol = strings.Replace(expectedVol, replaceNameStr, path, -1)
 		}
 		tempStr := fmt.Sprintf("%v", volumes)
 		if expectedVol != tempStr {
@@ -140,9 +137,9 @@ func TestDefineInputs(t *testing.T) {
 			t.Errorf("DefineInputs(%q, %q) == %v, expected %v", seedFileName, c.inputs, sizeStr, c.expectedSize)
 		}
 
-		tempStr = fmt.Sprintf("%v", tempDir)
-		if expectedTempDir != tempStr {
-			t.Errorf("DefineInputs(%q, %q) == \n%v, expected \n%v", seedFileName, c.inputs, tempStr, expectedTempDir)
+		tempStr = fmt.Sprintf("%v", staging.ContainerDirs())
+		if c.expectedStaged != tempStr {
+			t.Errorf("DefineInputs(%q, %q) == \n%v, expected \n%v", seedFileName, c.inputs, tempStr, c.expectedStaged)
 		}
 	}
 }
@@ -231,35 +228,50 @@ func TestDefineResources(t *testing.T) {
 	cases := []struct {
 		seedFileName     string
 		inputSize        float64
+		overrides        []string
+		cpuset           bool
 		expectedResource string
 		expectedOutSize  float64
 		expectedResult   bool
 		expectedErrorMsg string
 	}{
 		{"../examples/addition-job/seed.manifest.json",
-			4.0, "[-e ALLOCATED_CPUS=0.100000 -m 16m -e ALLOCATED_MEM=16 -e ALLOCATED_DISK=5.000000 --shm-size=128m -e ALLOCATED_SHAREDMEM=128]", 5.0, true, ""},
+			4.0, []string{}, false, "[--cpus=0.1 -e ALLOCATED_CPUS=0.100000 -m 16m -e ALLOCATED_MEM=16 -e ALLOCATED_DISK=5.000000 --shm-size=128m -e ALLOCATED_SHAREDMEM=128]", 5.0, true, ""},
 		{"../examples/extractor/seed.manifest.json",
-			1.0, "[-e ALLOCATED_CPUS=1.000000 -m 16m -e ALLOCATED_MEM=16 --shm-size=1m -e ALLOCATED_SHAREDMEM=1 -e ALLOCATED_DISK=1.010000]", 1.01, true, ""},
+			1.0, []string{}, false, "[--cpus=1 -e ALLOCATED_CPUS=1.000000 -m 16m -e ALLOCATED_MEM=16 --shm-size=1m -e ALLOCATED_SHAREDMEM=1 -e ALLOCATED_DISK=1.010000]", 1.01, true, ""},
 		{"../examples/extractor/seed.manifest.json",
-			16.0, "[-e ALLOCATED_CPUS=1.000000 -m 16m -e ALLOCATED_MEM=16 --shm-size=1m -e ALLOCATED_SHAREDMEM=1 -e ALLOCATED_DISK=16.010000]", 16.01, true, ""},
+			16.0, []string{}, false, "[--cpus=1 -e ALLOCATED_CPUS=1.000000 -m 16m -e ALLOCATED_MEM=16 --shm-size=1m -e ALLOCATED_SHAREDMEM=1 -e ALLOCATED_DISK=16.010000]", 16.01, true, ""},
+		{"../examples/extractor/seed.manifest.json",
+			1.0, []string{}, true, "[--cpuset-cpus=0 -e ALLOCATED_CPUS=1.000000 -m 16m -e ALLOCATED_MEM=16 --shm-size=1m -e ALLOCATED_SHAREDMEM=1 -e ALLOCATED_DISK=1.010000]", 1.01, true, ""},
+		{"../examples/addition-job/seed.manifest.json",
+			4.0, []string{"mem=64", "disk=max:2", "SHAREDMEM=x0.5"}, false, "[--cpus=0.1 -e ALLOCATED_CPUS=0.100000 -m 64m -e ALLOCATED_MEM=64 -e ALLOCATED_DISK=2.000000 --shm-size=64m -e ALLOCATED_SHAREDMEM=64]", 2.0, true, ""},
+		{"../examples/addition-job/seed.manifest.json",
+			4.0, []string{"all=x2", "cpus=min:0.5"}, false, "[--cpus=0.5 -e ALLOCATED_CPUS=0.500000 -m 32m -e ALLOCATED_MEM=32 -e ALLOCATED_DISK=10.000000 --shm-size=256m -e ALLOCATED_SHAREDMEM=256]", 10.0, true, ""},
+		{"../examples/addition-job/seed.manifest.json",
+			4.0, []string{"mem"}, false, "[]", 0.0, false, "should be specified in NAME=VALUE format"},
+		{"../examples/addition-job/seed.manifest.json",
+			4.0, []string{"mem=lots"}, false, "[]", 0.0, false, "Invalid value for resource override mem=lots"},
 	}
 
 	for _, c := range cases {
 		seedFileName := util.GetFullPath(c.seedFileName, "")
 		seed := objects.SeedFromManifestFile(seedFileName)
-		resources, outSize, err := DefineResources(&seed, c.inputSize)
+		resources, outSize, err := DefineResources(&seed, c.inputSize, c.overrides, c.cpuset)
 
 		if c.expectedResult != (err == nil) {
-			t.Errorf("DefineResources(%v, %v) returned unexpected error: %v", seedFileName, c.inputSize, err)
+			t.Errorf("DefineResources(%v, %v, %v, %v) returned unexpected error: %v", seedFileName, c.inputSize, c.overrides, c.cpuset, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), c.expectedErrorMsg) {
+			t.Errorf("DefineResources(%v, %v, %v, %v) == %v, expected %v", seedFileName, c.inputSize, c.overrides, c.cpuset, err.Error(), c.expectedErrorMsg)
 		}
 
 		tempStr := fmt.Sprintf("%v", resources)
 		if c.expectedResource != tempStr {
-			t.Errorf("DefineResources(%v, %v) == \n%v, expected \n%v", seedFileName, c.inputSize, tempStr, c.expectedResource)
+			t.Errorf("DefineResources(%v, %v, %v, %v) == \n%v, expected \n%v", seedFileName, c.inputSize, c.overrides, c.cpuset, tempStr, c.expectedResource)
 		}
 
 		if c.expectedOutSize != outSize {
-			t.Errorf("DefineResources(%v, %v) == \n%v, expected \n%v", seedFileName, c.inputSize, outSize, c.expectedOutSize)
+			t.Errorf("DefineResources(%v, %v, %v, %v) == \n%v, expected \n%v", seedFileName, c.inputSize, c.overrides, c.cpuset, outSize, c.expectedOutSize)
 
 		}
 	}
@@ -270,32 +282,33 @@ func TestDefineSettings(t *testing.T) {
 		seedFileName     string
 		settings         []string
 		expectedSet      string
+		expectedSecrets  string
 		expected         bool
 		expectedErrorMsg string
 	}{
 		{"../examples/addition-job/seed.manifest.json",
 			[]string{"SETTING_ONE=One", "SETTING_TWO=two"},
-			"[-e SETTING_ONE=One -e SETTING_TWO=two]", true, ""},
+			"[-e SETTING_ONE=One]", "[SETTING_TWO=two]", true, ""},
 		{"../examples/extractor/seed.manifest.json",
-			[]string{"HELLO=Hello"}, "[-e HELLO=Hello]", true, ""},
+			[]string{"HELLO=Hello"}, "[-e HELLO=Hello]", "[]", true, ""},
 		{"../testdata/complete/seed.manifest.json",
 			[]string{"version=1.0", "db-host=host", "db-pass=pass"},
-			"[-e VERSION=1.0 -e DB_HOST=host -e DB_PASS=pass]",
+			"[-e VERSION=1.0 -e DB_HOST=host]", "[DB_PASS=pass]",
 			true, ""},
 		{"../testdata/complete/seed.manifest.json",
 			[]string{"version=1.0"},
-			"[]",
+			"[]", "[]",
 			false, ""},
 		{"../testdata/complete-denormalized/seed.manifest.json",
 			[]string{"version=1.0", "db-host=host", "db-pass=pass"},
-			"[-e VERSION=1.0 -e DB_HOST=host -e DB_PASS=pass]",
+			"[-e VERSION=1.0 -e DB_HOST=host]", "[DB_PASS=pass]",
 			true, ""},
 	}
 
 	for _, c := range cases {
 		seedFileName := util.GetFullPath(c.seedFileName, "")
 		seed := objects.SeedFromManifestFile(seedFileName)
-		settings, err := DefineSettings(&seed, c.settings)
+		settings, secrets, err := DefineSettings(&seed, c.settings)
 
 		if c.expected != (err == nil) {
 			t.Errorf("DefineSettings(%q, %q) == %v, expected %v", seedFileName, c.settings, err, nil)
@@ -305,5 +318,636 @@ func TestDefineSettings(t *testing.T) {
 		if c.expectedSet != tempStr {
 			t.Errorf("DefineSettings(%q, %q) == \n%v, expected \n%v", seedFileName, c.settings, tempStr, c.expectedSet)
 		}
+
+		tempStr = fmt.Sprintf("%v", secrets)
+		if c.expectedSecrets != tempStr {
+			t.Errorf("DefineSettings(%q, %q) secrets == \n%v, expected \n%v", seedFileName, c.settings, tempStr, c.expectedSecrets)
+		}
+	}
+}
+
+func TestWaitForContainer(t *testing.T) {
+	cases := []struct {
+		sleep            string
+		timeout          int
+		expectedTimedOut bool
+	}{
+		{"0", 0, false},
+		{"0", 5, false},
+		{"30", 1, true},
+	}
+
+	for _, c := range cases {
+		cmd := exec.Command("sleep", c.sleep)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Error starting sleep command: %v", err)
+		}
+		start := time.Now()
+		timedOut, _ := waitForContainer(cmd, "seed-test-timeout", c.timeout)
+		if timedOut != c.expectedTimedOut {
+			t.Errorf("waitForContainer(sleep %s, %v) timed out == %v, expected %v", c.sleep, c.timeout, timedOut, c.expectedTimedOut)
+		}
+		if c.expectedTimedOut && time.Since(start) > 20*time.Second {
+			t.Errorf("waitForContainer(sleep %s, %v) did not stop the command after the timeout", c.sleep, c.timeout)
+		}
+	}
+}
+
+func TestCheckRunOutput(t *testing.T) {
+	seed := &objects.Seed{}
+	seed.Job.Interface.Outputs.Files = []objects.OutFile{
+		{Name: "TEXT", MediaType: "text/plain", Pattern: "out.*", Required: true},
+		{Name: "IMAGES", MediaType: "image/png", Pattern: "*.png", Multiple: true, Required: true},
+	}
+	seed.Job.Interface.Outputs.JSON = []objects.OutJson{
+		{Name: "COUNT", Type: "integer", Required: true},
+		{Name: "LABEL", Key: "label", Type: "string"},
+	}
+
+	cases := []struct {
+		files          map[string]string
+		diskLimit      float64
+		valid          bool
+		textMatches    int
+		imageMatches   int
+		mismatches     int
+		jsonErrorKeys  []string
+		diskExceeded   bool
+		expectedErrors []string
+		values         map[string]interface{}
+	}{
+		{map[string]string{"out.txt": "a", "a.png": "", "b.png": "", "seed.outputs.json": `{"COUNT": 1, "label": "x"}`},
+			0, true, 1, 2, 0, nil, false, nil, map[string]interface{}{"COUNT": int64(1), "LABEL": "x"}},
+		{map[string]string{"out.txt": "a", "c.png": "", "d.txt.png": "", "seed.outputs.json": `{"label": 2}`},
+			0, false, 1, 2, 0, []string{"COUNT", "label"}, false,
+			[]string{"seed.outputs.json is invalid", "seed.outputs.json is invalid"}, map[string]interface{}{}},
+		{map[string]string{"out.dat1": "", "a.png": "", "b.png": "", "seed.outputs.json": `{"COUNT": 1, "LABEL": "x"}`},
+			0, true, 1, 2, 0, nil, false, nil, map[string]interface{}{"COUNT": int64(1)}},
+		{map[string]string{"out.png": "", "b.jpg": "", "seed.outputs.json": `{"COUNT": 1}`},
+			0, false, 0, 1, 1, nil, false,
+			[]string{"Required file expected for output TEXT, 0 found", "Multiple required files expected for output IMAGES, 1 found"},
+			map[string]interface{}{"COUNT": int64(1)}},
+		{map[string]string{"out.txt": strings.Repeat("a", 2*1024*1024), "a.png": "", "b.png": ""},
+			1, false, 1, 2, 0, []string{""}, true,
+			[]string{"Output directory exceeds disk space limit", "seed.outputs.json specified but cannot be found"},
+			map[string]interface{}{}},
+	}
+
+	for i, c := range cases {
+		dir, err := ioutil.TempDir("", "seed-outputs-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range c.files {
+			ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		}
+
+		result, outputs := CheckRunOutput(seed, dir, "", c.diskLimit)
+		os.RemoveAll(dir)
+
+		if result.Valid() != c.valid {
+			t.Errorf("case %d: CheckRunOutput valid == %v, expected %v: %v", i, result.Valid(), c.valid, result.Errors())
+		}
+		if len(result.Files) != 2 || len(result.Files[0].Matches) != c.textMatches || len(result.Files[1].Matches) != c.imageMatches {
+			t.Errorf("case %d: CheckRunOutput returned file results %v, expected %d text and %d image matches",
+				i, result.Files, c.textMatches, c.imageMatches)
+		}
+		if len(outputs.Files["TEXT"]) != c.textMatches || len(outputs.Files["IMAGES"]) != c.imageMatches {
+			t.Errorf("case %d: CheckRunOutput returned output files %v, expected %d text and %d image files",
+				i, outputs.Files, c.textMatches, c.imageMatches)
+		}
+		if !reflect.DeepEqual(outputs.JSON, c.values) {
+			t.Errorf("case %d: CheckRunOutput returned json outputs %v, expected %v", i, outputs.JSON, c.values)
+		}
+		if len(result.Files[0].MediaTypeMismatches) != c.mismatches {
+			t.Errorf("case %d: CheckRunOutput returned media type mismatches %v, expected %d",
+				i, result.Files[0].MediaTypeMismatches, c.mismatches)
+		}
+		if result.DiskExceeded() != c.diskExceeded {
+			t.Errorf("case %d: CheckRunOutput disk exceeded == %v, expected %v", i, result.DiskExceeded(), c.diskExceeded)
+		}
+		var keys []string
+		for _, j := range result.JSON {
+			keys = append(keys, j.Key)
+		}
+		if !reflect.DeepEqual(keys, c.jsonErrorKeys) {
+			t.Errorf("case %d: CheckRunOutput returned json errors for keys %v, expected %v", i, keys, c.jsonErrorKeys)
+		}
+		errs := result.Errors()
+		if len(errs) != len(c.expectedErrors) {
+			t.Errorf("case %d: CheckRunOutput returned errors %v, expected %v", i, errs, c.expectedErrors)
+			continue
+		}
+		for j, e := range c.expectedErrors {
+			if !strings.Contains(errs[j], e) {
+				t.Errorf("case %d: CheckRunOutput returned error %v, expected %v", i, errs[j], e)
+			}
+		}
+	}
+}
+
+func TestOutputValues(t *testing.T) {
+	entries := []objects.OutJson{
+		{Name: "COUNT", Type: "integer"},
+		{Name: "SCORE", Key: `say "hi"`, Type: "number"},
+		{Name: "DONE", Type: "boolean"},
+		{Name: "TAGS", Type: "array"},
+		{Name: "BBOX", Type: "object"},
+		{Name: "LABEL", Type: "string"},
+	}
+
+	cases := []struct {
+		document string
+		expected map[string]interface{}
+	}{
+		{`{"COUNT": 3, "say \"hi\"": 0.5, "DONE": true, "TAGS": ["a"], "BBOX": {"x": 1}, "LABEL": "l"}`,
+			map[string]interface{}{"COUNT": int64(3), "SCORE": 0.5, "DONE": true, "TAGS": []interface{}{"a"},
+				"BBOX": map[string]interface{}{"x": json.Number("1")}, "LABEL": "l"}},
+		{`{"COUNT": 2.0, "SCORE": 1, "say \"hi\"": 7}`, map[string]interface{}{"COUNT": int64(2), "SCORE": 7.0}},
+		{`{"COUNT": 2.5, "DONE": "yes", "TAGS": {}, "BBOX": [], "LABEL": 1}`, map[string]interface{}{}},
+		{`["COUNT"]`, map[string]interface{}{}},
+		{`not json`, map[string]interface{}{}},
+	}
+
+	for _, c := range cases {
+		if values := outputValues(entries, []byte(c.document)); !reflect.DeepEqual(values, c.expected) {
+			t.Errorf("outputValues(%s) == %v, expected %v", c.document, values, c.expected)
+		}
+	}
+
+	// keys that would break a schema written as text are validated as any other
+	dir, err := ioutil.TempDir("", "seed-outputs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "seed.outputs.json"), []byte(`{"say \"hi\"": "x"}`), 0644)
+	seed := &objects.Seed{}
+	seed.Job.Interface.Outputs.JSON = []objects.OutJson{{Name: "SCORE", Key: `say "hi"`, Type: "number", Required: true}}
+	result, outputs := CheckRunOutput(seed, dir, "", 0)
+	if len(result.JSON) != 1 || result.JSON[0].Key != `say "hi"` || len(outputs.JSON) != 0 {
+		t.Errorf("CheckRunOutput with a quoted key returned json errors %v and outputs %v, expected a type error for the key",
+			result.JSON, outputs.JSON)
+	}
+}
+
+//tiffFile returns a little endian TIFF with one image directory holding the given tags
+func tiffFile(tags ...uint16) []byte {
+	data := []byte("II*\x00\x08\x00\x00\x00")
+	data = append(data, byte(len(tags)), 0)
+	for _, tag := range tags {
+		entry := make([]byte, 12)
+		entry[0], entry[1] = byte(tag), byte(tag>>8)
+		data = append(data, entry...)
+	}
+	return append(data, 0, 0, 0, 0)
+}
+
+func TestDetectMediaType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-media-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		name     string
+		contents []byte
+		expected string
+	}{
+		{"data.h5", []byte("\x89HDF\r\n\x1a\n\x00\x00"), "application/x-hdf5"},
+		{"misnamed.txt", []byte("\x89HDF\r\n\x1a\n\x00\x00"), "application/x-hdf5"},
+		{"image.ntf", []byte("NITF02.10"), "application/vnd.nitf"},
+		{"plain.tif", tiffFile(256, 257), "image/tiff"},
+		{"geo.tif", tiffFile(256, 257, 34735), "image/tiff; application=geotiff"},
+		{"image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), "image/png"},
+		{"results.json", []byte(`{"a": 1}`), "application/json"},
+		{"empty.h5", []byte{}, "application/x-hdf5"},
+		{"unknown.seedtest", []byte{0, 1, 2, 3}, "application/octet-stream"},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		ioutil.WriteFile(path, c.contents, 0644)
+		detected, err := DetectMediaType(path)
+		if err != nil {
+			t.Errorf("DetectMediaType(%v) returned an error: %v", c.name, err)
+		}
+		if detected != c.expected {
+			t.Errorf("DetectMediaType(%v) == %v, expected %v", c.name, detected, c.expected)
+		}
+	}
+}
+
+func TestMediaTypeMatches(t *testing.T) {
+	cases := []struct {
+		detected string
+		accepted []string
+		expected bool
+	}{
+		{"image/png", nil, true},
+		{"image/png", []string{"image/png"}, true},
+		{"image/png", []string{"image/tiff", "image/png"}, true},
+		{"image/png", []string{"image/tiff"}, false},
+		{"image/png", []string{"image/*"}, true},
+		{"application/json", []string{"image/*"}, false},
+		{"image/tiff; application=geotiff", []string{"image/tiff"}, true},
+		{"image/tiff", []string{"image/tiff; application=geotiff"}, false},
+		{"text/plain; charset=utf-8", []string{"text/plain"}, true},
+		{"text/plain; charset=utf-8", []string{"text/plain; charset=us-ascii"}, true},
+		{"application/x-hdf5", []string{"application/octet-stream"}, true},
+	}
+
+	for _, c := range cases {
+		if result := MediaTypeMatches(c.detected, c.accepted); result != c.expected {
+			t.Errorf("MediaTypeMatches(%v, %v) == %v, expected %v", c.detected, c.accepted, result, c.expected)
+		}
+	}
+}
+
+func TestCheckInputMediaTypes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-media-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	png := filepath.Join(dir, "image.png")
+	ioutil.WriteFile(png, []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0644)
+	geo := filepath.Join(dir, "geo.tif")
+	ioutil.WriteFile(geo, tiffFile(34735), 0644)
+	images := filepath.Join(dir, "images")
+	os.Mkdir(images, os.ModePerm)
+	ioutil.WriteFile(filepath.Join(images, "a.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0644)
+	os.Mkdir(filepath.Join(images, "sub"), os.ModePerm)
+	ioutil.WriteFile(filepath.Join(images, "sub", "b.h5"), []byte("\x89HDF\r\n\x1a\n"), 0644)
+
+	seed := &objects.Seed{}
+	seed.Job.Interface.Inputs.Files = []objects.InFile{
+		{Name: "IMAGE", MediaTypes: []string{"image/tiff"}},
+		{Name: "IMAGES", MediaTypes: []string{"image/png"}, Multiple: true},
+		{Name: "ANY"},
+	}
+
+	cases := []struct {
+		inputs   []string
+		filter   InputFilter
+		policy   string
+		errorMsg string
+	}{
+		{[]string{"IMAGE=" + geo, "IMAGES=" + filepath.Join(images, "a.png"), "ANY=" + png}, InputFilter{}, constants.MediaTypeFail, ""},
+		{[]string{"IMAGE=" + png}, InputFilter{}, constants.MediaTypeFail, "Input IMAGE file " + png + " has media type image/png, expected one of image/tiff"},
+		{[]string{"IMAGE=" + png}, InputFilter{}, constants.MediaTypeWarn, ""},
+		{[]string{"IMAGE=" + png}, InputFilter{}, constants.MediaTypeOff, ""},
+		{[]string{"IMAGES=" + images}, InputFilter{}, constants.MediaTypeFail, "b.h5 has media type application/x-hdf5, expected one of image/png"},
+		{[]string{"IMAGES=" + images}, InputFilter{Include: []string{"*.png"}}, constants.MediaTypeFail, ""},
+		{[]string{"IMAGES=" + images}, InputFilter{Exclude: []string{"sub"}}, constants.MediaTypeFail, ""},
+	}
+
+	for _, c := range cases {
+		err := CheckInputMediaTypes(seed, c.inputs, c.filter, c.policy)
+		if c.errorMsg == "" && err != nil {
+			t.Errorf("CheckInputMediaTypes(%v, %v) returned an error: %v", c.inputs, c.policy, err)
+		} else if c.errorMsg != "" && (err == nil || !strings.Contains(err.Error(), c.errorMsg)) {
+			t.Errorf("CheckInputMediaTypes(%v, %v) returned error %v, expected %v", c.inputs, c.policy, err, c.errorMsg)
+		}
+	}
+}
+
+func TestFileInputMap(t *testing.T) {
+	seed := objects.Seed{}
+	seed.Job.Interface.Inputs.Files = []objects.InFile{{Name: "INPUT_FILE"}, {Name: "MULTIPLE", Multiple: true}}
+
+	inputs := []string{"INPUT_FILE=a.txt", "multiple=data/*.tif", "INPUT_FILE=b.txt", "MULTIPLE=@files.txt", "MULTIPLE=c,d.tif"}
+	expected := map[string][]string{"INPUT_FILE": {"b.txt"}, "MULTIPLE": {"data/*.tif", "@files.txt", "c,d.tif"}}
+	if inMap := fileInputMap(&seed, inputs); !reflect.DeepEqual(inMap, expected) {
+		t.Errorf("fileInputMap(%q) == %v, expected %v", inputs, inMap, expected)
+	}
+}
+
+func TestDryRunNoCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-dry-run-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	seed := objects.SeedFromManifestFile(util.GetFullPath("../examples/extractor/seed.manifest.json", ""))
+	files := []string{"../testdata/seed-scale.zip", "../testdata/batch-test.csv"}
+	volumes, _, staging, err := defineInputs(&seed, []string{"ZIP=" + files[0], "MULTIPLE=" + files[0], "MULTIPLE=" + files[1]}, InputFilter{}, false)
+	if err != nil {
+		t.Errorf("defineInputs without creating returned an error: %v", err)
+	}
+	if _, err := os.Stat(staging.Dir); staging.Dir == "" || !os.IsNotExist(err) {
+		t.Errorf("defineInputs without creating made staging directory %v", staging.Dir)
+	}
+	if !strings.Contains(fmt.Sprintf("%v", volumes), "-e MULTIPLE=/seed-inputs/MULTIPLE") {
+		t.Errorf("defineInputs without creating returned %v, expected the staging directory to be mounted", volumes)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if outdir := resolveOutputDir("my-job", &seed, missing, false); outdir != missing {
+		t.Errorf("resolveOutputDir(%v) without creating returned %v, expected %v", missing, outdir, missing)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("resolveOutputDir without creating made output directory %v", missing)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "existing"), []byte{}, 0644)
+	outdir := resolveOutputDir("my-job", &seed, dir, false)
+	if filepath.Dir(outdir) != dir {
+		t.Errorf("resolveOutputDir(%v) of a non-empty directory returned %v, expected a sub-directory", dir, outdir)
+	}
+	if _, err := os.Stat(outdir); !os.IsNotExist(err) {
+		t.Errorf("resolveOutputDir without creating made output sub-directory %v", outdir)
+	}
+}
+
+func TestAllocatedResources(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected map[string]float64
+	}{
+		{[]string{"--cpus=2", "-e", "ALLOCATED_CPUS=2.000000", "-m", "512m", "-e", "ALLOCATED_MEM=512", "-e", "ALLOCATED_SHAREDMEM=64"},
+			map[string]float64{"cpus": 2, "mem": 512, "sharedmem": 64}},
+		{[]string{"-e", "OTHER=1", "-e"}, map[string]float64{}},
+		{nil, map[string]float64{}},
+	}
+
+	for _, c := range cases {
+		if result := allocatedResources(c.args); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("allocatedResources(%v) == %v, expected %v", c.args, result, c.expected)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"docker", "run", "-e", "OUTPUT_DIR=/tmp/out", "my-job:1.0"}, "docker run -e OUTPUT_DIR=/tmp/out my-job:1.0"},
+		{[]string{"-e", "MSG=hello world"}, "-e 'MSG=hello world'"},
+		{[]string{"-e", "MSG=it's"}, `-e 'MSG=it'\''s'`},
+		{[]string{"echo", "", "$HOME"}, "echo '' '$HOME'"},
+	}
+
+	for _, c := range cases {
+		if result := shellQuote(c.args); result != c.expected {
+			t.Errorf("shellQuote(%q) == %v, expected %v", c.args, result, c.expected)
+		}
+	}
+}
+
+func TestLoadJobSpec(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-job-spec-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "params.json"), []byte(`{"a": 1}`), 0644)
+
+	yamlSpec := `image: my-job-0.1.0-seed:1.0.0
+inputs:
+  INPUT_FILE: data/in.tif
+  AUX: /abs/aux.tif
+  TILES: [tiles/a.tif, /abs/b.tif]
+json:
+  PARAMS: params.json
+  COUNT: 3
+  OPTIONS: {mode: fast, bands: [1, 2]}
+settings:
+  VERSION: 1.0
+mounts:
+  REF: ref
+outputDir: out
+repeat: 2
+resources:
+  cpus: 2
+  mem: max:1024
+`
+	jsonSpec := `{"manifest": "seed.manifest.json", "settings": {"MODE": "fast", "LEVEL": 2.50}, "batch": "rows.csv"}`
+
+	cases := []struct {
+		spec      string
+		inputs    []string
+		json      []string
+		settings  []string
+		mounts    []string
+		resources []string
+		outputDir string
+		manifest  string
+		batch     string
+		repeat    int
+	}{
+		{yamlSpec,
+			[]string{"AUX=/abs/aux.tif", "INPUT_FILE=" + filepath.Join(dir, "data/in.tif"),
+				"TILES=/abs/b.tif", "TILES=" + filepath.Join(dir, "tiles/a.tif")},
+			[]string{"COUNT=3", `OPTIONS={"bands":[1,2],"mode":"fast"}`, "PARAMS=" + filepath.Join(dir, "params.json")},
+			[]string{"VERSION=1.0"},
+			[]string{"REF=" + filepath.Join(dir, "ref")},
+			[]string{"cpus=2", "mem=max:1024"},
+			filepath.Join(dir, "out"), "", "", 2},
+		{jsonSpec, nil, nil, []string{"LEVEL=2.50", "MODE=fast"}, nil, nil,
+			"", filepath.Join(dir, "seed.manifest.json"), filepath.Join(dir, "rows.csv"), 0},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, "job.yaml")
+		ioutil.WriteFile(path, []byte(c.spec), 0644)
+		spec, err := LoadJobSpec(path)
+		if err != nil {
+			t.Errorf("LoadJobSpec(%q) returned an error: %v", c.spec, err)
+			continue
+		}
+		results := [][]string{spec.InputArgs(), spec.JsonArgs(), spec.SettingArgs(), spec.MountArgs(), spec.ResourceArgs()}
+		expected := [][]string{c.inputs, c.json, c.settings, c.mounts, c.resources}
+		for i := range results {
+			if !reflect.DeepEqual(results[i], expected[i]) {
+				t.Errorf("LoadJobSpec(%q) args == %v, expected %v", c.spec, results[i], expected[i])
+			}
+		}
+		if spec.OutputDir != c.outputDir || spec.Manifest != c.manifest || spec.Batch != c.batch || spec.Repeat != c.repeat {
+			t.Errorf("LoadJobSpec(%q) == %+v, expected outputDir %v, manifest %v, batch %v and repeat %v",
+				c.spec, spec, c.outputDir, c.manifest, c.batch, c.repeat)
+		}
+	}
+}
+
+func TestJobSpecValidate(t *testing.T) {
+	seed := objects.Seed{}
+	seed.Job.Interface.Inputs.Files = []objects.InFile{{Name: "INPUT_FILE", Required: true}, {Name: "TILES", Multiple: true}}
+	seed.Job.Interface.Inputs.Json = []objects.InJson{{Name: "COUNT", Type: "integer"}}
+	seed.Job.Interface.Settings = []objects.Setting{{Name: "VERSION"}}
+	seed.Job.Interface.Mounts = []objects.Mount{{Name: "REF"}}
+
+	cases := []struct {
+		spec     string
+		batch    bool
+		errorMsg string
+	}{
+		{"inputs: {INPUT_FILE: in.tif}\njson: {COUNT: 3}\nsettings: {VERSION: 1.0}\nmounts: {REF: /ref}\nrepeat: 2", false, ""},
+		{"json: {COUNT: count.json}\nresources: {cpus: 2}", false, ""},
+		{"settings: {VERSION: 1.0}", false, ""},
+		{"inputs: {OTHER: in.tif}", false, "OTHER"},
+		{"inputs: {TILES: [a.tif, b.tif]}", false, ""},
+		{"inputs: {TILES: a.tif}", false, ""},
+		{"inputs: {INPUT_FILE: [a.tif, b.tif]}", false, "INPUT_FILE"},
+		{"json: {COUNT: [1]}", false, "COUNT"},
+		{"settings: {MODE: fast}", false, "MODE"},
+		{"mounts: {OTHER: /ref}", false, "OTHER"},
+		{"repeat: 0", false, "repeat"},
+		{"images: my-job", false, "images"},
+		{"batch: rows.csv\nsettings: {VERSION: 1.0}", true, ""},
+		{"inputs: {INPUT_FILE: in.tif}", true, "inputs"},
+		{"batch: rows.csv", false, "batch"},
+	}
+
+	dir, err := ioutil.TempDir("", "seed-job-spec-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, c := range cases {
+		path := filepath.Join(dir, "job.yaml")
+		ioutil.WriteFile(path, []byte(c.spec), 0644)
+		spec, err := LoadJobSpec(path)
+		if err != nil {
+			t.Errorf("LoadJobSpec(%q) returned an error: %v", c.spec, err)
+			continue
+		}
+		err = spec.Validate(&seed, c.batch)
+		if c.errorMsg == "" && err != nil {
+			t.Errorf("Validate(%q, %v) returned an error: %v", c.spec, c.batch, err)
+		} else if c.errorMsg != "" && (err == nil || !strings.Contains(err.Error(), c.errorMsg)) {
+			t.Errorf("Validate(%q, %v) returned error %v, expected %v", c.spec, c.batch, err, c.errorMsg)
+		}
+	}
+}
+
+func TestMergeArgs(t *testing.T) {
+	cases := []struct {
+		fileArgs []string
+		flagArgs []string
+		expected []string
+	}{
+		{[]string{"A=1", "B=2"}, []string{""}, []string{"A=1", "B=2"}},
+		{[]string{"A=1", "B=2"}, []string{"b=3"}, []string{"A=1", "b=3"}},
+		{[]string{"mem=max:1024"}, []string{"cpus=2", "mem=512"}, []string{"cpus=2", "mem=512"}},
+		{nil, []string{"A=1"}, []string{"A=1"}},
+		{nil, nil, nil},
+	}
+
+	for _, c := range cases {
+		if result := MergeArgs(c.fileArgs, c.flagArgs); !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("MergeArgs(%v, %v) == %v, expected %v", c.fileArgs, c.flagArgs, result, c.expected)
+		}
+	}
+}
+
+func TestPrepareDebugSession(t *testing.T) {
+	seed := objects.Seed{}
+	seed.Job.Interface.Inputs.Files = []objects.InFile{{Name: "input-file"}}
+	seed.Job.Interface.Inputs.Json = []objects.InJson{{Name: "PARAMS", Type: "object"}}
+	runArgs := []string{"-v", "/data/in.tif:/data/in.tif:ro", "-e", "INPUT_FILE=/data/in.tif", "-v", "/out:/out",
+		"-e", "OUTPUT_DIR=/out", "-e", `PARAMS={"a": 1}`, "-e", "MODE=fast"}
+	jobArgs := []string{"process", "/data/in.tif", "/out", "--pass=hunter2"}
+	secrets := []string{"DB_PASS=hunter2"}
+
+	session, err := prepareDebugSession("my-job:1.0", "seed-my-job-1", &seed, []string{"python", "app.py"}, jobArgs, runArgs, secrets, true)
+	defer os.RemoveAll(session.Dir)
+	if err != nil {
+		t.Fatalf("prepareDebugSession returned an error: %v", err)
+	}
+
+	script, err := ioutil.ReadFile(filepath.Join(session.Dir, constants.DebugScript))
+	if err != nil {
+		t.Fatalf("prepareDebugSession did not write the debug script: %v", err)
+	}
+	if string(script) != session.Script {
+		t.Errorf("prepareDebugSession wrote %q, expected %q", script, session.Script)
+	}
+	for _, name := range []string{session.Dir, filepath.Join(session.Dir, constants.DebugScript)} {
+		if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0700 {
+			t.Errorf("prepareDebugSession made %v with mode %v, expected it only accessible by the user", name, info.Mode())
+		}
+	}
+
+	command := `python app.py process /data/in.tif /out --pass="${DB_PASS}"`
+	for _, expected := range []string{"OUTPUT_DIR: /out", "INPUT_FILE=/data/in.tif", `PARAMS={"a": 1}`, command} {
+		if !strings.Contains(session.Banner, expected) {
+			t.Errorf("prepareDebugSession banner %q doesn't contain %q", session.Banner, expected)
+		}
+	}
+	if strings.Contains(session.Banner, "MODE=fast") {
+		t.Errorf("prepareDebugSession banner %q lists setting MODE as an input", session.Banner)
+	}
+	if !strings.HasPrefix(session.Script, "#!/bin/sh\n") || !strings.HasSuffix(session.Script, "\n"+command+"\n") {
+		t.Errorf("prepareDebugSession script %q, expected a shell script ending with %q", session.Script, command)
+	}
+
+	args := strings.Join(session.Args, " ")
+	for _, expected := range []string{"-v " + session.Dir + ":" + constants.DebugScriptDir, "--entrypoint " + constants.DebugShell} {
+		if !strings.Contains(args, expected) {
+			t.Errorf("prepareDebugSession args %q don't contain %q", args, expected)
+		}
+	}
+
+	dryRun, _ := prepareDebugSession("my-job:1.0", "seed-my-job-2", &seed, nil, jobArgs, runArgs, secrets, false)
+	if _, err := os.Stat(dryRun.Dir); !os.IsNotExist(err) {
+		t.Errorf("prepareDebugSession without creating made script directory %v", dryRun.Dir)
+	}
+}
+
+func TestNewRunRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-run-record-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Mkdir(filepath.Join(dir, "multi"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "in.txt"), []byte("hello\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "multi", "a.txt"), []byte{}, 0644)
+
+	seed := objects.Seed{SeedVersion: "1.0.0"}
+	seed.Job.Name = "my-job"
+	seed.Job.JobVersion = "0.1.0"
+	seed.Job.Interface.Inputs.Files = []objects.InFile{{Name: "INPUT_FILE"}, {Name: "MULTIPLE", Multiple: true}, {Name: "MISSING"}}
+	seed.Job.Interface.Settings = []objects.Setting{{Name: "MODE"}, {Name: "TOKEN", Secret: true}, {Name: "UNSET"}}
+	seed.Job.Interface.Mounts = []objects.Mount{{Name: "REF"}}
+
+	inputs := []string{"INPUT_FILE=" + filepath.Join(dir, "in.txt"), "MULTIPLE=" + filepath.Join(dir, "multi"),
+		"MISSING=" + filepath.Join(dir, "missing.txt")}
+	record := NewRunRecord("my-job-0.1.0-seed:1.0.0", &seed, inputs, InputFilter{}, []string{"MODE=fast"}, []string{"REF=" + dir},
+		[]string{"TOKEN=abc123"})
+
+	expectedInputs := []InputRecord{
+		{Name: "INPUT_FILE", Path: filepath.Join(dir, "in.txt"), Size: 6,
+			SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+		{Name: "MULTIPLE", Path: filepath.Join(dir, "multi", "a.txt"), Size: 0,
+			SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+	}
+	if len(record.Inputs) != 3 || !reflect.DeepEqual(record.Inputs[:2], expectedInputs) || record.Inputs[2].Error == "" {
+		t.Errorf("NewRunRecord inputs == %+v, expected %+v and an error for the missing input", record.Inputs, expectedInputs)
+	}
+	expectedSettings := map[string]string{"MODE": "fast", "TOKEN": RedactedValue}
+	if !reflect.DeepEqual(record.Settings, expectedSettings) {
+		t.Errorf("NewRunRecord settings == %v, expected %v", record.Settings, expectedSettings)
+	}
+	if !reflect.DeepEqual(record.Mounts, map[string]string{"REF": dir}) {
+		t.Errorf("NewRunRecord mounts == %v, expected REF=%v", record.Mounts, dir)
+	}
+	if record.SeedVersion != "1.0.0" || record.JobName != "my-job" || record.JobVersion != "0.1.0" || record.Host == "" {
+		t.Errorf("NewRunRecord == %+v, expected the seed version, job name and version and host", record)
+	}
+
+	record.ExitCode = 3
+	if err := WriteRunRecord(record, dir); err != nil {
+		t.Fatalf("WriteRunRecord returned an error: %v", err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, constants.RunRecordFile))
+	if !strings.Contains(string(data), `"exitCode": 3`) || strings.Contains(string(data), "abc123") {
+		t.Errorf("WriteRunRecord wrote %s, expected the exit code without the secret value", data)
 	}
 }
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//RedactedValue replaces secret setting values in any printed output
+const RedactedValue = "******"
+
+//RedactMinLength is the shortest secret setting value that is redacted. Shorter values
+// would redact common words and numbers throughout the output.
+const RedactMinLength = 4
+
+//redactLineMax is the longest partial line a redactWriter holds back waiting for the
+// rest of it
+const redactLineMax = 64 * 1024
+
+//LoadSecrets returns the values of the secret settings defined in the seed manifest
+// in KEY=VALUE form. Values are read from the environment variable with the
+// normalized setting name, then from secretsFile if one is given. Values in
+// secretsFile take precedence over the environment.
+func LoadSecrets(seed *objects.Seed, secretsFile string) ([]string, error) {
+	fileSecrets := make(map[string]string)
+	if secretsFile != "" {
+		lines, err := util.ReadLinesFromFile(util.GetFullPath(secretsFile, ""))
+		if err != nil {
+			return nil, fmt.Errorf("ERROR: Error reading secrets file %s: %s\n", secretsFile, err.Error())
+		}
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			x := strings.SplitN(line, "=", 2)
+			if len(x) != 2 {
+				return nil, fmt.Errorf("ERROR: Secrets file %s line %d should be in the form KEY=VALUE\n", secretsFile, i+1)
+			}
+			fileSecrets[util.GetNormalizedVariable(strings.TrimSpace(x[0]))] = x[1]
+		}
+	}
+
+	var secrets []string
+	for _, s := range seed.Job.Interface.Settings {
+		if !s.Secret {
+			continue
+		}
+		normalName := util.GetNormalizedVariable(s.Name)
+		if value, ok := fileSecrets[normalName]; ok {
+			secrets = append(secrets, normalName+"="+value)
+		} else if value, ok := os.LookupEnv(normalName); ok {
+			secrets = append(secrets, normalName+"="+value)
+		}
+	}
+
+	return secrets, nil
+}
+
+//SecretSettings returns the KEY=VALUE pairs from settings that are marked secret
+// in the seed manifest
+func SecretSettings(seed *objects.Seed, settings []string) []string {
+	inMap := inputMap(settings, true)
+
+	var secrets []string
+	for _, s := range seed.Job.Interface.Settings {
+		normalName := util.GetNormalizedVariable(s.Name)
+		if value, ok := inMap[normalName]; ok && s.Secret {
+			secrets = append(secrets, normalName+"="+value)
+		}
+	}
+	return secrets
+}
+
+//WriteEnvFile writes the given KEY=VALUE pairs to a new temp file in docker
+// --env-file format that is only readable by the current user. The caller is
+// responsible for removing the file.
+func WriteEnvFile(env []string) (string, error) {
+	for _, e := range env {
+		if strings.ContainsAny(e, "\r\n") {
+			key := strings.SplitN(e, "=", 2)[0]
+			return "", errors.New("ERROR: Secret setting " + key + " cannot contain a newline.\n")
+		}
+	}
+
+	file, err := ioutil.TempFile("", "seed-env-")
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err = file.WriteString(strings.Join(env, "\n") + "\n"); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+//WarnShortSecrets warns about each secret setting whose value is too short to be
+// redacted from printed output
+func WarnShortSecrets(secrets []string) {
+	warned := make(map[string]bool)
+	for _, s := range secrets {
+		x := strings.SplitN(s, "=", 2)
+		if len(x) != 2 || x[1] == "" || len(x[1]) >= RedactMinLength || warned[x[0]] {
+			continue
+		}
+		warned[x[0]] = true
+		util.PrintUtil("WARNING: Secret setting %s is shorter than %d characters and will not be redacted from output.\n", x[0], RedactMinLength)
+	}
+}
+
+//RedactSecrets replaces the value of every KEY=VALUE pair in secrets found in str
+// with RedactedValue. Values shorter than RedactMinLength are left as they are.
+func RedactSecrets(str string, secrets []string) string {
+	for _, s := range secrets {
+		x := strings.SplitN(s, "=", 2)
+		if len(x) != 2 || len(x[1]) < RedactMinLength {
+			continue
+		}
+		str = strings.Replace(str, x[1], RedactedValue, -1)
+	}
+	return str
+}
+
+//redactWriter redacts secrets from the output written through it. Output is passed on
+// a line at a time so a secret split across two writes is still caught.
+type redactWriter struct {
+	w       io.Writer
+	secrets []string
+	buf     []byte
+}
+
+func newRedactWriter(w io.Writer, secrets []string) *redactWriter {
+	return &redactWriter{w: w, secrets: secrets}
+}
+
+func (r *redactWriter) Write(p []byte) (int, error) {
+	if len(r.secrets) == 0 {
+		return r.w.Write(p)
+	}
+	r.buf = append(r.buf, p...)
+	end := bytes.LastIndexByte(r.buf, '\n') + 1
+	if end == 0 && len(r.buf) < redactLineMax {
+		return len(p), nil
+	}
+	if end == 0 {
+		end = len(r.buf)
+	}
+	_, err := io.WriteString(r.w, RedactSecrets(string(r.buf[:end]), r.secrets))
+	r.buf = append(r.buf[:0], r.buf[end:]...)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+//Flush writes any partial last line held back
+func (r *redactWriter) Flush() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(r.w, RedactSecrets(string(r.buf), r.secrets))
+	r.buf = r.buf[:0]
+	return err
+}
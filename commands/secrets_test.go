@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ngageoint/seed-common/objects"
+	"github.com/ngageoint/seed-common/util"
+)
+
+func TestLoadSecrets(t *testing.T) {
+	secretsFile, err := ioutil.TempFile("", "seed-secrets-test-")
+	if err != nil {
+		t.Fatalf("Error creating secrets file: %v", err)
+	}
+	defer os.Remove(secretsFile.Name())
+	secretsFile.WriteString("# database credentials\n\ndb-pass=fromfile\nVERSION=ignored\n")
+	secretsFile.Close()
+
+	badFile, err := ioutil.TempFile("", "seed-secrets-test-")
+	if err != nil {
+		t.Fatalf("Error creating secrets file: %v", err)
+	}
+	defer os.Remove(badFile.Name())
+	badFile.WriteString("DB_PASS\n")
+	badFile.Close()
+
+	cases := []struct {
+		seedFileName     string
+		secretsFile      string
+		env              string
+		expected         string
+		expectedErrorMsg string
+	}{
+		{"../testdata/complete/seed.manifest.json", "", "", "[]", ""},
+		{"../testdata/complete/seed.manifest.json", "", "fromenv", "[DB_PASS=fromenv]", ""},
+		{"../testdata/complete/seed.manifest.json", secretsFile.Name(), "", "[DB_PASS=fromfile]", ""},
+		{"../testdata/complete/seed.manifest.json", secretsFile.Name(), "fromenv", "[DB_PASS=fromfile]", ""},
+		{"../examples/addition-job/seed.manifest.json", secretsFile.Name(), "fromenv", "[]", ""},
+		{"../testdata/complete/seed.manifest.json", badFile.Name(), "", "[]", "should be in the form KEY=VALUE"},
+		{"../testdata/complete/seed.manifest.json", "missing-secrets-file", "", "[]", "Error reading secrets file"},
+	}
+
+	for _, c := range cases {
+		os.Unsetenv("DB_PASS")
+		if c.env != "" {
+			os.Setenv("DB_PASS", c.env)
+		}
+		seedFileName := util.GetFullPath(c.seedFileName, "")
+		seed := objects.SeedFromManifestFile(seedFileName)
+		secrets, err := LoadSecrets(&seed, c.secretsFile)
+
+		tempStr := fmt.Sprintf("%v", secrets)
+		if c.expected != tempStr {
+			t.Errorf("LoadSecrets(%q, %q) == %v, expected %v", c.seedFileName, c.secretsFile, tempStr, c.expected)
+		}
+		if (err != nil) != (c.expectedErrorMsg != "") {
+			t.Errorf("LoadSecrets(%q, %q) returned error %v, expected %q", c.seedFileName, c.secretsFile, err, c.expectedErrorMsg)
+		} else if err != nil && !strings.Contains(err.Error(), c.expectedErrorMsg) {
+			t.Errorf("LoadSecrets(%q, %q) == %v, expected %v", c.seedFileName, c.secretsFile, err.Error(), c.expectedErrorMsg)
+		}
+	}
+	os.Unsetenv("DB_PASS")
+}
+
+func TestSecretSettings(t *testing.T) {
+	cases := []struct {
+		seedFileName string
+		settings     []string
+		expected     string
+	}{
+		{"../testdata/complete/seed.manifest.json", []string{"version=1.0", "db-host=host", "db-pass=pass"}, "[DB_PASS=pass]"},
+		{"../testdata/complete/seed.manifest.json", []string{"version=1.0"}, "[]"},
+		{"../examples/extractor/seed.manifest.json", []string{"HELLO=Hello"}, "[]"},
+	}
+
+	for _, c := range cases {
+		seed := objects.SeedFromManifestFile(util.GetFullPath(c.seedFileName, ""))
+		tempStr := fmt.Sprintf("%v", SecretSettings(&seed, c.settings))
+		if c.expected != tempStr {
+			t.Errorf("SecretSettings(%q, %q) == %v, expected %v", c.seedFileName, c.settings, tempStr, c.expected)
+		}
+	}
+}
+
+func TestWriteEnvFile(t *testing.T) {
+	envFile, err := WriteEnvFile([]string{"DB_PASS=p@ss word", "TOKEN=abc=123"})
+	if err != nil {
+		t.Fatalf("WriteEnvFile returned unexpected error: %v", err)
+	}
+	defer os.Remove(envFile)
+
+	info, err := os.Stat(envFile)
+	if err != nil {
+		t.Fatalf("Error reading env file %s: %v", envFile, err)
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		t.Errorf("WriteEnvFile created %s with mode %v, expected it to be readable only by the owner", envFile, info.Mode().Perm())
+	}
+
+	contents, _ := ioutil.ReadFile(envFile)
+	expected := "DB_PASS=p@ss word\nTOKEN=abc=123\n"
+	if string(contents) != expected {
+		t.Errorf("WriteEnvFile wrote %q, expected %q", string(contents), expected)
+	}
+
+	if _, err = WriteEnvFile([]string{"DB_PASS=line1\nline2"}); err == nil {
+		t.Errorf("WriteEnvFile with a multi-line secret returned no error")
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	cases := []struct {
+		str      string
+		secrets  []string
+		expected string
+	}{
+		{"docker run -e DB_HOST=host image", []string{}, "docker run -e DB_HOST=host image"},
+		{"./run.sh hunter2 host", []string{"DB_PASS=hunter2"}, "./run.sh ****** host"},
+		{"token abc=123 and pass", []string{"TOKEN=abc=123", "DB_PASS=pass", "EMPTY="}, "token ****** and ******"},
+		{"processed 1 of 10 as pin 123", []string{"PIN=123", "ID=1"}, "processed 1 of 10 as pin 123"},
+	}
+
+	for _, c := range cases {
+		redacted := RedactSecrets(c.str, c.secrets)
+		if redacted != c.expected {
+			t.Errorf("RedactSecrets(%q, %q) == %q, expected %q", c.str, c.secrets, redacted, c.expected)
+		}
+	}
+}
+
+func TestRedactWriter(t *testing.T) {
+	var out strings.Builder
+	w := newRedactWriter(&out, []string{"DB_PASS=hunter2"})
+	for _, write := range []string{"connecting with hun", "ter2\nconnected", " as hunter2"} {
+		w.Write([]byte(write))
+	}
+	if out.String() != "connecting with ******\n" {
+		t.Errorf("redactWriter wrote %q before the end of the line, expected only the first line redacted", out.String())
+	}
+	w.Flush()
+	if expected := "connecting with ******\nconnected as ******"; out.String() != expected {
+		t.Errorf("redactWriter wrote %q, expected %q", out.String(), expected)
+	}
+}
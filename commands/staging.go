@@ -0,0 +1,416 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ngageoint/seed-cli/constants"
+	"github.com/ngageoint/seed-common/util"
+)
+
+//Strategies used to stage the files of a multiple file input, from the cheapest to the
+// most expensive. A directory given as the only file of an input is mounted as is.
+const (
+	StageHardlink = "hardlink"
+	StageSymlink  = "symlink"
+	StageCopy     = "copy"
+	StageMount    = "mount"
+)
+
+//ScratchDir returns the directory multiple file inputs are staged under: SEED_SCRATCH_DIR
+// if it is set, otherwise the system temp directory
+func ScratchDir() string {
+	if dir := os.Getenv(constants.ScratchDirEnv); dir != "" {
+		return util.GetFullPath(dir, "")
+	}
+	return os.TempDir()
+}
+
+//InputFilter selects the files of directories given for multiple file inputs. Patterns
+// are matched against the path of each file within the directory and against its name.
+type InputFilter struct {
+	// Include are the patterns of the files to stage; all files if empty
+	Include []string
+
+	// Exclude are the patterns of the files and subdirectories to leave out
+	Exclude []string
+}
+
+//Expands returns whether directories are expanded into their files, which they are
+// when any patterns are given. Otherwise directories are mounted as they are.
+func (f InputFilter) Expands() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0
+}
+
+//Matches returns whether the file at rel within an expanded directory is staged
+func (f InputFilter) Matches(rel string) bool {
+	if matchAny(f.Exclude, rel) {
+		return false
+	}
+	return len(f.Include) == 0 || matchAny(f.Include, rel)
+}
+
+//matchAny returns whether rel or its base name matches any of patterns
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(p, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+//InputFile is a file or directory given for a multiple file input
+type InputFile struct {
+	// Path is the full path of the file or directory
+	Path string
+
+	// Name is where the file is staged within the input's staging directory: its base
+	// name, or its path within an expanded directory
+	Name string
+}
+
+//StagedInput is a multiple file input staged in a directory mounted into the job container
+type StagedInput struct {
+	Name string
+
+	// Files are the files and directories given for the input
+	Files []InputFile
+
+	// Size is the total size of the files in bytes, including the files in directories
+	Size int64
+
+	// HostDir is the directory the files are staged in, or the directory given for the input
+	HostDir string
+
+	// ContainerDir is where HostDir is mounted in the container
+	ContainerDir string
+
+	// Strategy is how the files were staged, the most expensive strategy if files needed
+	// different ones. Empty if the files weren't staged because the staging directory
+	// wasn't created.
+	Strategy string
+
+	// mounts are the docker -v args for directories given among the files and for the
+	// targets of symlinked files, which only resolve in the container if mounted
+	mounts []string
+}
+
+//Args returns the docker args mounting the staged input and setting its variable
+func (in *StagedInput) Args() []string {
+	args := []string{"-v", in.HostDir + ":" + in.ContainerDir, "-e", in.Name + "=" + in.ContainerDir}
+	return append(args, in.mounts...)
+}
+
+//Staging is a uniquely named directory under the scratch directory holding the multiple
+// file inputs of a run, so runs and inputs staged at the same time never collide
+type Staging struct {
+	// Dir is the staging directory, only created once a multiple input is staged in it
+	Dir string
+
+	// Filter selects the files of directories given for the inputs
+	Filter InputFilter
+
+	Inputs map[string]*StagedInput
+
+	create  bool
+	created bool
+
+	// strategies are the strategies tried to stage each file, in order
+	strategies []string
+}
+
+//NewStaging returns a staging area under the scratch directory. The staging directory
+// is only created, and files only staged, if create is true.
+func NewStaging(create bool) *Staging {
+	return &Staging{Inputs: map[string]*StagedInput{}, create: create,
+		strategies: []string{StageHardlink, StageSymlink, StageCopy}}
+}
+
+//ContainerDirs returns the container directory of each staged input by name
+func (s *Staging) ContainerDirs() map[string]string {
+	dirs := map[string]string{}
+	for name, in := range s.Inputs {
+		dirs[name] = in.ContainerDir
+	}
+	return dirs
+}
+
+//Stage stages the files given for the multiple input name, see resolveInputFiles for
+// the forms values may take. The files are linked into a directory named for the input,
+// trying a hardlink, then a symlink, then a copy of each file.
+func (s *Staging) Stage(name string, values []string) (*StagedInput, error) {
+	in := &StagedInput{Name: name, ContainerDir: path.Join(constants.StagingContainerDir, name)}
+	s.Inputs[name] = in
+
+	files, err := resolveInputFiles(values, s.Filter)
+	if err != nil {
+		return in, err
+	}
+	in.Files = files
+	for _, f := range files {
+		in.Size += pathSize(f.Path)
+	}
+
+	// a directory given on its own is mounted rather than staged
+	if len(files) == 1 {
+		if info, err := os.Stat(files[0].Path); err == nil && info.IsDir() {
+			in.HostDir = files[0].Path
+			in.Strategy = StageMount
+			return in, nil
+		}
+	}
+
+	if s.Dir == "" {
+		if !s.create {
+			s.Dir = filepath.Join(ScratchDir(), "seed-staging-"+strconv.Itoa(os.Getpid()))
+		} else {
+			if err := os.MkdirAll(ScratchDir(), 0755); err != nil {
+				return in, fmt.Errorf("ERROR: Error creating scratch directory %s.\n%v", ScratchDir(), err)
+			}
+			if s.Dir, err = ioutil.TempDir(ScratchDir(), "seed-staging-"); err != nil {
+				return in, fmt.Errorf("ERROR: Error creating staging directory in %s.\n%v", ScratchDir(), err)
+			}
+			s.created = true
+		}
+	}
+	in.HostDir = filepath.Join(s.Dir, name)
+
+	names := map[string]string{}
+	for _, f := range files {
+		if other, ok := names[f.Name]; ok {
+			return in, fmt.Errorf("ERROR: Input %s has more than one file named %s: %s and %s", name, f.Name, other, f.Path)
+		}
+		names[f.Name] = f.Path
+	}
+	if !s.create {
+		return in, nil
+	}
+
+	if err := os.Mkdir(in.HostDir, 0755); err != nil {
+		return in, fmt.Errorf("ERROR: Error creating staging directory for input %s.\n%v", name, err)
+	}
+	rank := map[string]int{}
+	for i, strategy := range s.strategies {
+		rank[strategy] = i
+	}
+	for _, f := range files {
+		target := filepath.Join(in.HostDir, f.Name)
+		info, err := os.Stat(f.Path)
+		if err == nil {
+			// files of expanded directories keep their layout
+			err = os.MkdirAll(filepath.Dir(target), 0755)
+		}
+		if err != nil {
+			return in, fmt.Errorf("ERROR: Error staging input %s.\n%v", name, err)
+		}
+		if info.IsDir() {
+			// directories among the files are mounted inside the staging directory
+			if err := os.Mkdir(target, 0755); err != nil {
+				return in, fmt.Errorf("ERROR: Error staging input %s.\n%v", name, err)
+			}
+			in.mounts = append(in.mounts, "-v", f.Path+":"+path.Join(in.ContainerDir, filepath.ToSlash(f.Name))+":ro")
+			continue
+		}
+
+		strategy, err := stageFile(f.Path, target, info, s.strategies)
+		if err != nil {
+			return in, fmt.Errorf("ERROR: Error staging file %s for input %s.\n%v", f.Path, name, err)
+		}
+		if strategy == StageSymlink {
+			in.mounts = append(in.mounts, "-v", f.Path+":"+f.Path+":ro")
+		}
+		if in.Strategy == "" || rank[strategy] > rank[in.Strategy] {
+			in.Strategy = strategy
+		}
+	}
+	if in.Strategy == "" {
+		in.Strategy = StageMount
+	}
+	util.PrintUtil("INFO: Staged %d files (%.2f MiB) for input %s in %s by %s\n", len(files),
+		float64(in.Size)/(1024.0*1024.0), name, in.HostDir, in.Strategy)
+	return in, nil
+}
+
+//Cleanup removes the staging directory. The staged files are links or copies, so the
+// files given for the inputs are left alone.
+func (s *Staging) Cleanup() {
+	if s.created {
+		os.RemoveAll(s.Dir)
+	}
+}
+
+//stageFile stages file at target with the first of strategies that succeeds, returning
+// the strategy used. Hardlinks fail across filesystems and symlinks on some mounts, so
+// each is tried in turn.
+func stageFile(file, target string, info os.FileInfo, strategies []string) (string, error) {
+	var errs []string
+	for _, strategy := range strategies {
+		var err error
+		switch strategy {
+		case StageHardlink:
+			err = os.Link(file, target)
+		case StageSymlink:
+			err = os.Symlink(file, target)
+		case StageCopy:
+			err = copyFile(file, target, info.Mode().Perm())
+		}
+		if err == nil {
+			return strategy, nil
+		}
+		errs = append(errs, strategy+": "+err.Error())
+		os.Remove(target)
+	}
+	return "", fmt.Errorf("%s", strings.Join(errs, "\n"))
+}
+
+//resolveInputPaths returns the full paths of the files and directories given for a
+// multiple file input, see resolveInputFiles
+func resolveInputPaths(values []string, filter InputFilter) ([]string, error) {
+	files, err := resolveInputFiles(values, filter)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths, nil
+}
+
+//resolveInputFiles returns the files and directories given for a multiple file input.
+// Each of values is a path, a glob pattern such as data/*.tif or a list file named with
+// a leading @ giving a path or pattern per line. Blank lines and lines
+// starting with # in list files are ignored, and relative paths in them are relative to
+// the list file. If filter has patterns, directories are replaced by the files in them
+// and their subdirectories that the patterns select. Returns an error if a path doesn't
+// exist, a pattern matches nothing or no files are left.
+func resolveInputFiles(values []string, filter InputFilter) ([]InputFile, error) {
+	var files []InputFile
+	seen := map[string]bool{}
+	add := func(p, name string) {
+		if !seen[p] {
+			seen[p] = true
+			files = append(files, InputFile{Path: p, Name: name})
+		}
+	}
+	addPath := func(p string) error {
+		info, err := os.Stat(p)
+		if err != nil {
+			return fmt.Errorf("ERROR: Input file %s not found", p)
+		}
+		if !info.IsDir() || !filter.Expands() {
+			add(p, filepath.Base(p))
+			return nil
+		}
+		return filepath.Walk(p, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return fmt.Errorf("ERROR: Error reading input directory %s.\n%v", p, err)
+			}
+			rel, _ := filepath.Rel(p, file)
+			if info.IsDir() {
+				if rel != "." && matchAny(filter.Exclude, rel) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Mode().IsRegular() && filter.Matches(rel) {
+				add(file, rel)
+			}
+			return nil
+		})
+	}
+
+	var resolve func(entry, dir string) error
+	resolve = func(entry, dir string) error {
+		if strings.HasPrefix(entry, "@") {
+			listFile := util.GetFullPath(entry[1:], dir)
+			entries, err := readListFile(listFile)
+			if err != nil {
+				return fmt.Errorf("ERROR: Error reading input list file %s.\n%v", listFile, err)
+			}
+			for _, e := range entries {
+				if err := resolve(e, filepath.Dir(listFile)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		full := util.GetFullPath(entry, dir)
+		if strings.ContainsAny(entry, "*?[") {
+			matches, err := filepath.Glob(full)
+			if err != nil {
+				return fmt.Errorf("ERROR: Invalid input pattern %s.\n%v", entry, err)
+			}
+			if len(matches) == 0 {
+				return fmt.Errorf("ERROR: Input pattern %s matches no files", entry)
+			}
+			sort.Strings(matches)
+			for _, m := range matches {
+				if err := addPath(m); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return addPath(full)
+	}
+
+	for _, entry := range values {
+		if entry == "" {
+			continue
+		}
+		if err := resolve(entry, ""); err != nil {
+			return nil, err
+		}
+	}
+	if len(files) == 0 && filter.Expands() {
+		return nil, fmt.Errorf("ERROR: No input files in %s match the include and exclude patterns", strings.Join(values, ", "))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("ERROR: No input files given in %s", strings.Join(values, ", "))
+	}
+	return files, nil
+}
+
+//pathSize returns the size in bytes of an input file, or of the files in an input directory
+func pathSize(p string) int64 {
+	info, err := os.Stat(p)
+	if err != nil {
+		return 0
+	}
+	if info.IsDir() {
+		return dirSize(p)
+	}
+	return info.Size()
+}
+
+//readListFile returns the entries of an input list file
+func readListFile(listFile string) ([]string, error) {
+	file, err := os.Open(listFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ngageoint/seed-cli/constants"
+)
+
+func TestResolveInputPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-inputs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.tif", "b.tif", "c.txt", "x,y.txt", "sub/d.tif", "sub/e.txt", "sub/tmp/f.tif"} {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "files.txt"), []byte("# inputs\na.tif\n\n  sub/*.tif  \n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "missing.txt"), []byte("missing.tif\n"), 0644)
+	in := func(names ...string) []string {
+		var paths []string
+		for _, n := range names {
+			paths = append(paths, filepath.Join(dir, n))
+		}
+		return paths
+	}
+
+	cases := []struct {
+		values   []string
+		filter   InputFilter
+		expected []string
+		errorMsg string
+	}{
+		{[]string{dir + "/a.tif"}, InputFilter{}, in("a.tif"), ""},
+		{[]string{dir + "/sub"}, InputFilter{}, in("sub"), ""},
+		{[]string{dir + "/*.tif"}, InputFilter{}, in("a.tif", "b.tif"), ""},
+		{[]string{dir + "/c.txt", dir + "/*.tif"}, InputFilter{}, in("c.txt", "a.tif", "b.tif"), ""},
+		{[]string{"@" + dir + "/files.txt"}, InputFilter{}, in("a.tif", "sub/d.tif"), ""},
+		{[]string{"@" + dir + "/files.txt", dir + "/a.tif"}, InputFilter{}, in("a.tif", "sub/d.tif"), ""},
+		{[]string{dir + "/x,y.txt"}, InputFilter{}, in("x,y.txt"), ""},
+		{[]string{dir + "/missing.tif"}, InputFilter{}, nil, "not found"},
+		{[]string{dir + "/*.png"}, InputFilter{}, nil, "matches no files"},
+		{[]string{"@" + dir + "/missing.txt"}, InputFilter{}, nil, "not found"},
+		{[]string{"@" + dir + "/none.txt"}, InputFilter{}, nil, "Error reading input list file"},
+		{[]string{""}, InputFilter{}, nil, "No input files"},
+		{[]string{dir + "/sub"}, InputFilter{Include: []string{"*.tif"}}, in("sub/d.tif", "sub/tmp/f.tif"), ""},
+		{[]string{dir + "/sub"}, InputFilter{Exclude: []string{"tmp"}}, in("sub/d.tif", "sub/e.txt"), ""},
+		{[]string{dir + "/sub", dir + "/c.txt"}, InputFilter{Include: []string{"*.tif"}, Exclude: []string{"tmp/*"}},
+			in("sub/d.tif", "c.txt"), ""},
+		{[]string{dir + "/s*"}, InputFilter{Include: []string{"tmp/*.tif"}}, in("sub/tmp/f.tif"), ""},
+		{[]string{dir + "/sub"}, InputFilter{Include: []string{"*.png"}}, nil, "match the include and exclude patterns"},
+	}
+
+	for _, c := range cases {
+		paths, err := resolveInputPaths(c.values, c.filter)
+		if c.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errorMsg) {
+				t.Errorf("resolveInputPaths(%q) returned error %v, expected %q", c.values, err, c.errorMsg)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(paths, c.expected) {
+			t.Errorf("resolveInputPaths(%q) == %q, %v, expected %q", c.values, paths, err, c.expected)
+		}
+	}
+}
+
+func TestStaging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seed-staging-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv(constants.ScratchDirEnv, filepath.Join(dir, "scratch"))
+	defer os.Unsetenv(constants.ScratchDirEnv)
+
+	data := filepath.Join(dir, "data")
+	for _, name := range []string{"a.tif", "b.tif", "other/a.tif", "sub/c.tif"} {
+		os.MkdirAll(filepath.Join(data, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(data, name), []byte(name), 0644)
+	}
+
+	cases := []struct {
+		values     []string
+		strategies []string
+		strategy   string
+		staged     []string
+		mounts     []string
+		errorMsg   string
+	}{
+		{[]string{data + "/*.tif"}, nil, StageHardlink, []string{"a.tif", "b.tif"}, nil, ""},
+		{[]string{data + "/*.tif"}, []string{StageSymlink, StageCopy}, StageSymlink, []string{"a.tif", "b.tif"},
+			[]string{"-v", data + "/a.tif:" + data + "/a.tif:ro", "-v", data + "/b.tif:" + data + "/b.tif:ro"}, ""},
+		{[]string{data + "/a.tif"}, []string{StageCopy}, StageCopy, []string{"a.tif"}, nil, ""},
+		{[]string{data + "/a.tif", data + "/sub"}, nil, StageHardlink, []string{"a.tif", "sub"},
+			[]string{"-v", data + "/sub:/seed-inputs/INPUT/sub:ro"}, ""},
+		{[]string{data + "/sub"}, nil, StageMount, nil, nil, ""},
+		{[]string{data + "/a.tif", data + "/other/a.tif"}, nil, "", nil, nil, "more than one file named a.tif"},
+		{[]string{data + "/missing.tif"}, nil, "", nil, nil, "not found"},
+	}
+
+	for _, c := range cases {
+		staging := NewStaging(true)
+		if c.strategies != nil {
+			staging.strategies = c.strategies
+		}
+		in, err := staging.Stage("INPUT", c.values)
+		if c.errorMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errorMsg) {
+				t.Errorf("Stage(%q) returned error %v, expected %q", c.values, err, c.errorMsg)
+			}
+			staging.Cleanup()
+			continue
+		}
+		if err != nil {
+			t.Errorf("Stage(%q) returned an error: %v", c.values, err)
+			staging.Cleanup()
+			continue
+		}
+		if in.Strategy != c.strategy {
+			t.Errorf("Stage(%q) used strategy %v, expected %v", c.values, in.Strategy, c.strategy)
+		}
+		if !reflect.DeepEqual(in.mounts, c.mounts) {
+			t.Errorf("Stage(%q) mounts == %q, expected %q", c.values, in.mounts, c.mounts)
+		}
+		if c.staged == nil {
+			if in.HostDir != data+"/sub" || staging.Dir != "" {
+				t.Errorf("Stage(%q) staged a directory given on its own in %v, expected it mounted", c.values, in.HostDir)
+			}
+		} else {
+			if filepath.Dir(staging.Dir) != filepath.Join(dir, "scratch") || in.HostDir != filepath.Join(staging.Dir, "INPUT") {
+				t.Errorf("Stage(%q) staged in %v, expected INPUT in a directory under the scratch directory", c.values, in.HostDir)
+			}
+			var names []string
+			files, _ := ioutil.ReadDir(in.HostDir)
+			for _, f := range files {
+				names = append(names, f.Name())
+			}
+			if !reflect.DeepEqual(names, c.staged) {
+				t.Errorf("Stage(%q) staged %q, expected %q", c.values, names, c.staged)
+			}
+		}
+		if args := in.Args(); args[1] != in.HostDir+":/seed-inputs/INPUT" || args[3] != "INPUT=/seed-inputs/INPUT" {
+			t.Errorf("Stage(%q) args == %q, expected %s mounted at /seed-inputs/INPUT", c.values, args, in.HostDir)
+		}
+
+		staging.Cleanup()
+		if _, err := os.Stat(data + "/a.tif"); err != nil {
+			t.Errorf("Cleanup after Stage(%q) removed an input file", c.values)
+		}
+		if _, err := os.Stat(staging.Dir); staging.Dir != "" && !os.IsNotExist(err) {
+			t.Errorf("Cleanup after Stage(%q) left staging directory %v", c.values, staging.Dir)
+		}
+	}
+
+	// files of an expanded directory keep their layout, so the same name in two
+	// subdirectories doesn't collide
+	expanded := NewStaging(true)
+	expanded.Filter = InputFilter{Include: []string{"*.tif"}, Exclude: []string{"b.tif"}}
+	in, err := expanded.Stage("INPUT", []string{data})
+	if err != nil {
+		t.Errorf("Stage of an expanded directory returned an error: %v", err)
+	} else {
+		for _, name := range []string{"a.tif", "other/a.tif", "sub/c.tif"} {
+			if _, err := os.Stat(filepath.Join(in.HostDir, name)); err != nil {
+				t.Errorf("Stage of an expanded directory didn't stage %v", name)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(in.HostDir, "b.tif")); !os.IsNotExist(err) {
+			t.Errorf("Stage of an expanded directory staged excluded file b.tif")
+		}
+		if in.Size != int64(len("a.tif")+len("other/a.tif")+len("sub/c.tif")) {
+			t.Errorf("Stage of an expanded directory size == %v, expected the size of the 3 staged files", in.Size)
+		}
+	}
+	expanded.Cleanup()
+
+	// runs staging the same input at the same time never share a directory
+	first, second := NewStaging(true), NewStaging(true)
+	defer first.Cleanup()
+	defer second.Cleanup()
+	first.Stage("INPUT", []string{data + "/a.tif"})
+	second.Stage("INPUT", []string{data + "/a.tif"})
+	if first.Dir == second.Dir {
+		t.Errorf("two stagings share directory %v", first.Dir)
+	}
+
+	// a dry run resolves the files without creating anything
+	dryRun := NewStaging(false)
+	in, err = dryRun.Stage("INPUT", []string{data + "/*.tif"})
+	if err != nil || len(in.Files) != 2 || in.Strategy != "" {
+		t.Errorf("Stage without creating == %v, %v, expected 2 files and no strategy", in, err)
+	}
+	if _, err := os.Stat(dryRun.Dir); !os.IsNotExist(err) {
+		t.Errorf("Stage without creating made staging directory %v", dryRun.Dir)
+	}
+}
@@ -16,6 +16,7 @@ const SearchCommand = "search"
 const ValidateCommand = "validate"
 const VersionCommand = "version"
 const SpecCommand = "spec"
+const CacheCommand = "cache"
 
 //CacheFromFlag defines the docker cache-from option to utilize a previous built image
 const CacheFromFlag = "cache-from"
@@ -169,3 +170,173 @@ const ShortWarnAsErrorsFlag = "w"
 
 //WarnAsErrorsFlag defines whether to treat warnings as errors
 const WarnAsErrorsFlag = "warnings"
+
+//TimeoutFlag defines the job timeout in seconds; overrides job.timeout from the seed manifest
+const TimeoutFlag = "timeout"
+
+//ShortTimeoutFlag shorthand flag that defines the job timeout in seconds
+const ShortTimeoutFlag = "t"
+
+//TimeoutExitCode defines the exit code returned when a job exceeds its timeout
+const TimeoutExitCode = 124
+
+//StopGracePeriod defines how many seconds a timed out container is given to exit before it is killed
+const StopGracePeriod = 10
+
+//ResourcesFlag defines overrides for the scalar resources specified in the seed manifest
+const ResourcesFlag = "resources"
+
+//CPUSetFlag defines whether to pin a job to specific CPUs instead of limiting its CPU time
+const CPUSetFlag = "cpuset"
+
+//MediaTypesFlag defines the policy for input files whose media type doesn't match the seed manifest
+const MediaTypesFlag = "input-media-types"
+
+//MediaTypeWarn prints a warning for input files with the wrong media type
+const MediaTypeWarn = "warn"
+
+//MediaTypeFail fails the job before the container starts for input files with the wrong media type
+const MediaTypeFail = "fail"
+
+//MediaTypeOff skips checking the media type of input files
+const MediaTypeOff = "off"
+
+//UnknownMediaType defines the media type of files whose type can't be determined
+const UnknownMediaType = "application/octet-stream"
+
+//InputIncludeFlag defines patterns of the files to stage from directories given for multiple file inputs
+const InputIncludeFlag = "input-include"
+
+//InputExcludeFlag defines patterns of the files to leave out of directories given for multiple file inputs
+const InputExcludeFlag = "input-exclude"
+
+//DryRunFlag defines whether to print the prepared docker run command instead of running it
+const DryRunFlag = "dry-run"
+
+//DebugFlag defines whether to start an interactive shell in the prepared job container instead of running the job
+const DebugFlag = "debug"
+
+//DebugShell defines the shell started in place of the job by seed run -debug
+const DebugShell = "/bin/sh"
+
+//DebugScriptDir defines the directory in a debug container holding the job script
+const DebugScriptDir = "/seed-debug"
+
+//DebugScript defines the name of the script that runs the job in a debug container
+const DebugScript = "run-job.sh"
+
+//JobSpecFlag defines a YAML or JSON file describing the job to run
+const JobSpecFlag = "file"
+
+//ShortJobSpecFlag - shorthand flag for file
+const ShortJobSpecFlag = "f"
+
+//OutputFormatFlag defines the format seed run prints its results in
+const OutputFormatFlag = "output-format"
+
+//FormatText defines plain text output
+const FormatText = "text"
+
+//FormatJSON defines JSON output
+const FormatJSON = "json"
+
+//StrictOutputsFlag defines whether a run fails when its output fails validation
+const StrictOutputsFlag = "strict-outputs"
+
+//SecretsFlag defines a file containing the values of secret settings
+const SecretsFlag = "secrets"
+
+//DockerExecEnv defines an environment variable that, when set, makes seed run the docker
+// command instead of talking to the Docker Engine API
+const DockerExecEnv = "SEED_DOCKER_EXEC"
+
+//RuntimeFlag defines the container runtime used to build and run images
+const RuntimeFlag = "runtime"
+
+//RuntimeEnv defines an environment variable that selects the container runtime when the runtime flag isn't given
+const RuntimeEnv = "SEED_RUNTIME"
+
+//DockerRuntime defines the name of the docker container runtime
+const DockerRuntime = "docker"
+
+//PodmanRuntime defines the name of the podman container runtime
+const PodmanRuntime = "podman"
+
+//ParallelFlag defines the number of batch jobs to run at once
+const ParallelFlag = "parallel"
+
+//ScratchDirEnv defines an environment variable giving the directory multiple file inputs are staged under
+const ScratchDirEnv = "SEED_SCRATCH_DIR"
+
+//StagingContainerDir defines the container directory staged multiple file inputs are mounted under
+const StagingContainerDir = "/seed-inputs"
+
+//StdoutLogFile defines the name of the file a job's stdout is written to within its output directory
+const StdoutLogFile = "stdout.log"
+
+//StderrLogFile defines the name of the file a job's stderr is written to within its output directory
+const StderrLogFile = "stderr.log"
+
+//LogTimestampsFlag defines whether each line of the job log files is prefixed with a timestamp
+const LogTimestampsFlag = "log-timestamps"
+
+//StderrBufferSize defines the most bytes of a job's stderr kept in memory to print when the job ends
+const StderrBufferSize = 64 * 1024
+
+//ResumeFlag defines whether to resume a batch, skipping jobs that already succeeded
+const ResumeFlag = "resume"
+
+//RetryFailedFlag defines the number of times failed batch jobs are retried
+const RetryFailedFlag = "retry-failed"
+
+//BatchFilePrefix defines the batch file key prefix of a file input column
+const BatchFilePrefix = "file:"
+
+//BatchJsonPrefix defines the batch file key prefix of a JSON input column
+const BatchJsonPrefix = "json:"
+
+//BatchSettingPrefix defines the batch file key prefix of a setting column
+const BatchSettingPrefix = "setting:"
+
+//BatchOutdirColumn defines the batch file key of the column naming each row's output directory
+const BatchOutdirColumn = "outdir"
+
+//NoCacheFlag defines whether to run a job even if its output is in the result cache
+const NoCacheFlag = "no-cache"
+
+//CacheDirEnv defines an environment variable that enables the result cache in the given directory
+const CacheDirEnv = "SEED_CACHE_DIR"
+
+//CacheSizeEnv defines an environment variable giving the size limit of the result cache in MiB
+const CacheSizeEnv = "SEED_CACHE_SIZE"
+
+//CacheMountsEnv defines an environment variable that, if set, lets runs with mounts use the
+// result cache even though the contents of the mounted directories aren't part of the key
+const CacheMountsEnv = "SEED_CACHE_MOUNTS"
+
+//DefaultCacheSize defines the size limit of the result cache in MiB when CacheSizeEnv isn't set
+const DefaultCacheSize = 10240
+
+//CacheListCommand defines the seed cache subcommand listing cached outputs
+const CacheListCommand = "ls"
+
+//CachePruneCommand defines the seed cache subcommand removing cached outputs
+const CachePruneCommand = "prune"
+
+//MaxSizeFlag defines the size in MiB to prune the result cache to
+const MaxSizeFlag = "max-size"
+
+//AllFlag defines whether to remove every entry from the result cache
+const AllFlag = "all"
+
+//RunRecordFile defines the name of the file recording the provenance of a run in the job output directory
+const RunRecordFile = "seed.run.json"
+
+//BatchJournalFile defines the name of the file recording the state of each batch job in the batch output directory
+const BatchJournalFile = "seed.batch.json"
+
+//ReportFlag defines the format of the batch report
+const ReportFlag = "report"
+
+//BatchReportFile defines the name, without extension, of the batch report written to the batch output directory
+const BatchReportFile = "seed.batch.report"
@@ -0,0 +1,235 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+//DefaultSocket is the Docker Engine API socket used when DOCKER_HOST is not set
+const DefaultSocket = "/var/run/docker.sock"
+
+//APIVersion is the Docker Engine API version requested by the client
+const APIVersion = "v1.25"
+
+//Client talks to the Docker Engine API over a unix socket
+type Client struct {
+	socket string
+	http   *http.Client
+}
+
+//Error is returned when the engine responds to a request with an error status
+type Error struct {
+	Op         string
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("docker %s failed (status %d): %s", e.Op, e.StatusCode, e.Message)
+}
+
+//StreamError is returned when a build, pull or push reports an error in its
+// progress stream. The engine responds to these requests with a success status
+// before the operation has completed.
+type StreamError struct {
+	Op      string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("docker %s failed: %s", e.Op, e.Message)
+}
+
+//IsNotFound returns whether err reports a missing image or container
+func IsNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.StatusCode == http.StatusNotFound
+}
+
+//AuthConfig holds the registry credentials sent with pull, push and build requests
+type AuthConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+}
+
+//NewClient returns a client for the engine socket given by DOCKER_HOST, or the
+// default socket if DOCKER_HOST is not set. Returns an error if DOCKER_HOST is
+// not a unix socket or the engine does not respond.
+func NewClient() (*Client, error) {
+	socket := DefaultSocket
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		if !strings.HasPrefix(host, "unix://") {
+			return nil, fmt.Errorf("unsupported DOCKER_HOST %s; only unix sockets are supported", host)
+		}
+		socket = strings.TrimPrefix(host, "unix://")
+	}
+
+	client := NewSocketClient(socket)
+	if err := client.Ping(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+//NewSocketClient returns a client for the engine listening on the given unix socket
+func NewSocketClient(socket string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &Client{socket: socket, http: &http.Client{Transport: transport}}
+}
+
+//Ping checks that the engine is reachable
+func (c *Client) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := c.doContext(ctx, "ping", "GET", "/_ping", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+//do sends a request to the engine. Responses with an error status are returned
+// as an *Error; otherwise the caller must close the response body.
+func (c *Client) do(op, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	return c.doContext(context.Background(), op, method, path, query, body, header)
+}
+
+func (c *Client) doContext(ctx context.Context, op, method, path string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	u := "http://docker/" + APIVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker %s failed: %s", op, err.Error())
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &msg) != nil || msg.Message == "" {
+			msg.Message = strings.TrimSpace(string(b))
+		}
+		return nil, &Error{Op: op, StatusCode: resp.StatusCode, Message: msg.Message}
+	}
+
+	return resp, nil
+}
+
+//doJSON sends a request with an optional JSON body and decodes any JSON response into out
+func (c *Client) doJSON(op, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = strings.NewReader(string(b))
+	}
+
+	resp, err := c.do(op, method, path, query, body, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+//encodeAuth encodes registry credentials for the X-Registry-Auth header
+func encodeAuth(auth *AuthConfig) string {
+	if auth == nil {
+		auth = &AuthConfig{}
+	}
+	b, _ := json.Marshal(auth)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+//streamMessage is a single progress message from a build, pull or push
+type streamMessage struct {
+	Stream      string `json:"stream"`
+	Status      string `json:"status"`
+	Progress    string `json:"progress"`
+	ID          string `json:"id"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+//readStream copies the progress messages from a build, pull or push response to
+// out. Returns a *StreamError if the engine reports the operation failed.
+func readStream(op string, body io.Reader, out io.Writer) error {
+	if out == nil {
+		out = ioutil.Discard
+	}
+
+	decoder := json.NewDecoder(body)
+	for {
+		var msg streamMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("docker %s failed: error reading response: %s", op, err.Error())
+		}
+
+		if msg.Error != "" || msg.ErrorDetail.Message != "" {
+			message := msg.ErrorDetail.Message
+			if message == "" {
+				message = msg.Error
+			}
+			return &StreamError{Op: op, Message: message}
+		}
+
+		if msg.Stream != "" {
+			fmt.Fprint(out, msg.Stream)
+		} else if msg.Status != "" {
+			line := msg.Status
+			if msg.ID != "" {
+				line = msg.ID + ": " + line
+			}
+			if msg.Progress != "" {
+				line += " " + msg.Progress
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+}
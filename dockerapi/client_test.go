@@ -0,0 +1,211 @@
+package dockerapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//testServer serves handler on a unix socket and returns a client for it
+func testServer(t *testing.T, handler http.Handler) (*Client, func()) {
+	dir, err := ioutil.TempDir("", "seed-dockerapi-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: handler}
+	go server.Serve(listener)
+
+	return NewSocketClient(socket), func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+//frame returns a multiplexed log frame for the given stream
+func frame(stream byte, data string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(data)))
+	return append(header, data...)
+}
+
+func TestClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+APIVersion+"/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	mux.HandleFunc("/"+APIVersion+"/images/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+APIVersion+"/images/my-job:1.0/json" {
+			w.Write([]byte(`{"Id":"sha256:abc","RepoTags":["my-job:1.0"],"Config":{"Labels":{"a":"b"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such image"}`))
+	})
+	mux.HandleFunc("/"+APIVersion+"/build", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"stream":"Step 1/2 : FROM alpine\n"}` + "\n"))
+		w.Write([]byte(`{"errorDetail":{"message":"pull access denied"},"error":"pull access denied"}` + "\n"))
+	})
+	mux.HandleFunc("/"+APIVersion+"/containers/abc/wait", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"StatusCode":3}`))
+	})
+	mux.HandleFunc("/"+APIVersion+"/containers/abc/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(frame(1, "out\n"))
+		w.Write(frame(2, "err\n"))
+		w.Write(frame(1, "more\n"))
+	})
+
+	client, done := testServer(t, mux)
+	defer done()
+
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping returned an error: %v", err)
+	}
+
+	image, err := client.InspectImage("my-job:1.0")
+	if err != nil {
+		t.Errorf("InspectImage returned an error: %v", err)
+	} else if image.ID != "sha256:abc" || image.Config.Labels["a"] != "b" {
+		t.Errorf("InspectImage returned %v", image)
+	}
+
+	_, err = client.InspectImage("missing")
+	if !IsNotFound(err) {
+		t.Errorf("InspectImage of a missing image returned %v, expected a not found error", err)
+	}
+	if exists, err := client.ImageExists("missing"); exists || err != nil {
+		t.Errorf("ImageExists of a missing image returned %v, %v", exists, err)
+	}
+
+	dir, err := ioutil.TempDir("", "seed-build-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644)
+
+	var out bytes.Buffer
+	err = client.BuildImage(BuildOptions{ContextDir: dir, Tag: "my-job:1.0"}, &out)
+	if _, ok := err.(*StreamError); !ok {
+		t.Errorf("BuildImage returned %v, expected a stream error", err)
+	}
+	if out.String() != "Step 1/2 : FROM alpine\n" {
+		t.Errorf("BuildImage output was %q", out.String())
+	}
+
+	code, err := client.WaitContainer("abc")
+	if code != 3 || err != nil {
+		t.Errorf("WaitContainer returned %v, %v", code, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := client.ContainerLogs("abc", true, &stdout, &stderr); err != nil {
+		t.Errorf("ContainerLogs returned an error: %v", err)
+	}
+	if stdout.String() != "out\nmore\n" || stderr.String() != "err\n" {
+		t.Errorf("ContainerLogs wrote stdout %q and stderr %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestConfigFromRunArgs(t *testing.T) {
+	envFile, err := ioutil.TempFile("", "seed-env-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(envFile.Name())
+	envFile.WriteString("# secrets\nDB_PASS=secret\n")
+	envFile.Close()
+
+	cases := []struct {
+		args     []string
+		expected RunConfig
+		errStr   string
+	}{
+		{[]string{"--rm", "--name", "seed-job-1", "-v", "/in:/in", "-e", "INPUT=/in", "my-job:1.0", "-i", "$INPUT"},
+			RunConfig{Name: "seed-job-1", AutoRemove: true, Config: ContainerConfig{Image: "my-job:1.0",
+				Cmd: []string{"-i", "$INPUT"}, Env: []string{"INPUT=/in"},
+				HostConfig: HostConfig{Binds: []string{"/in:/in"}}}}, ""},
+		{[]string{"-m", "512m", "--shm-size=64m", "--cpus=1.5", "--runtime=nvidia", "--env-file", envFile.Name(), "my-job:1.0"},
+			RunConfig{Config: ContainerConfig{Image: "my-job:1.0", Env: []string{"DB_PASS=secret"},
+				HostConfig: HostConfig{Memory: 512 << 20, ShmSize: 64 << 20, NanoCPUs: 1500000000, Runtime: "nvidia"}}}, ""},
+		{[]string{"my-job:1.0", "-o", "", "$OUTPUT"},
+			RunConfig{Config: ContainerConfig{Image: "my-job:1.0", Cmd: []string{"-o", "", "$OUTPUT"}}}, ""},
+		{[]string{"--cpuset-cpus=0-3", "my-job:1.0"},
+			RunConfig{Config: ContainerConfig{Image: "my-job:1.0", HostConfig: HostConfig{CpusetCpus: "0-3"}}}, ""},
+		{[]string{"--privileged", "my-job:1.0"}, RunConfig{}, "unsupported docker run option --privileged"},
+		{[]string{"-m", "lots", "my-job:1.0"}, RunConfig{}, "invalid value for docker run option -m"},
+		{[]string{"--rm"}, RunConfig{}, "no image given"},
+	}
+
+	for _, c := range cases {
+		run, err := ConfigFromRunArgs(c.args)
+		if c.errStr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errStr) {
+				t.Errorf("ConfigFromRunArgs(%v) returned error %v, expected %v", c.args, err, c.errStr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ConfigFromRunArgs(%v) returned an error: %v", c.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(*run, c.expected) {
+			t.Errorf("ConfigFromRunArgs(%v) returned %+v, expected %+v", c.args, *run, c.expected)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	cases := []struct {
+		name string
+		repo string
+		tag  string
+	}{
+		{"my-job:1.0", "my-job", "1.0"},
+		{"my-job", "my-job", "latest"},
+		{"localhost:5000/org/my-job", "localhost:5000/org/my-job", "latest"},
+		{"localhost:5000/org/my-job:1.0", "localhost:5000/org/my-job", "1.0"},
+		{"my-job@sha256:abc", "my-job@sha256:abc", ""},
+	}
+
+	for _, c := range cases {
+		repo, tag := parseReference(c.name)
+		if repo != c.repo || tag != c.tag {
+			t.Errorf("parseReference(%v) returned %v, %v, expected %v, %v", c.name, repo, tag, c.repo, c.tag)
+		}
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	patterns := []string{"*.log", "data", "docs/*.md", "!docs/README.md"}
+	cases := []struct {
+		path     string
+		expected bool
+	}{
+		{"run.log", true},
+		{"run.go", false},
+		{"data", true},
+		{"data/input.tif", true},
+		{"docs/guide.md", true},
+		{"docs/README.md", false},
+	}
+
+	for _, c := range cases {
+		if excluded := isExcluded(c.path, patterns); excluded != c.expected {
+			t.Errorf("isExcluded(%v) returned %v, expected %v", c.path, excluded, c.expected)
+		}
+	}
+}
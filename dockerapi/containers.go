@@ -0,0 +1,142 @@
+package dockerapi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+)
+
+//ContainerConfig describes a container to create
+type ContainerConfig struct {
+	Image      string     `json:"Image"`
+	Cmd        []string   `json:"Cmd,omitempty"`
+	Entrypoint []string   `json:"Entrypoint,omitempty"`
+	Env        []string   `json:"Env,omitempty"`
+	Tty        bool       `json:"Tty,omitempty"`
+	OpenStdin  bool       `json:"OpenStdin,omitempty"`
+	HostConfig HostConfig `json:"HostConfig"`
+}
+
+//HostConfig holds the resource limits and mounts for a container
+type HostConfig struct {
+	Binds      []string `json:"Binds,omitempty"`
+	Memory     int64    `json:"Memory,omitempty"`
+	ShmSize    int64    `json:"ShmSize,omitempty"`
+	NanoCPUs   int64    `json:"NanoCpus,omitempty"`
+	CpusetCpus string   `json:"CpusetCpus,omitempty"`
+	Runtime    string   `json:"Runtime,omitempty"`
+}
+
+//CreateContainer creates a container with the given name and returns its ID
+func (c *Client) CreateContainer(name string, config ContainerConfig) (string, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+
+	var created struct {
+		ID       string   `json:"Id"`
+		Warnings []string `json:"Warnings"`
+	}
+	if err := c.doJSON("create", "POST", "/containers/create", query, config, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+//StartContainer starts a created container
+func (c *Client) StartContainer(id string) error {
+	return c.doJSON("start", "POST", "/containers/"+id+"/start", nil, nil, nil)
+}
+
+//WaitContainer blocks until the container exits and returns its exit code
+func (c *Client) WaitContainer(id string) (int, error) {
+	var result struct {
+		StatusCode int `json:"StatusCode"`
+	}
+	if err := c.doJSON("wait", "POST", "/containers/"+id+"/wait", nil, nil, &result); err != nil {
+		return -1, err
+	}
+	return result.StatusCode, nil
+}
+
+//StopContainer stops the container, giving it gracePeriod seconds to exit before
+// the engine kills it
+func (c *Client) StopContainer(id string, gracePeriod int) error {
+	query := url.Values{}
+	query.Set("t", strconv.Itoa(gracePeriod))
+	return c.doJSON("stop", "POST", "/containers/"+id+"/stop", query, nil, nil)
+}
+
+//KillContainer kills the container
+func (c *Client) KillContainer(id string) error {
+	return c.doJSON("kill", "POST", "/containers/"+id+"/kill", nil, nil, nil)
+}
+
+//RemoveContainer removes the container and its anonymous volumes
+func (c *Client) RemoveContainer(id string, force bool) error {
+	query := url.Values{}
+	query.Set("v", "1")
+	if force {
+		query.Set("force", "1")
+	}
+	return c.doJSON("remove", "DELETE", "/containers/"+id, query, nil, nil)
+}
+
+//ContainerLogs copies the container's stdout and stderr to the given writers.
+// If follow is true it returns once the container exits. Nil writers discard
+// that stream.
+func (c *Client) ContainerLogs(id string, follow bool, stdout, stderr io.Writer) error {
+	query := url.Values{}
+	query.Set("stdout", "1")
+	query.Set("stderr", "1")
+	if follow {
+		query.Set("follow", "1")
+	}
+
+	resp, err := c.do("logs", "GET", "/containers/"+id+"/logs", query, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return demuxStream(resp.Body, stdout, stderr)
+}
+
+//demuxStream splits a multiplexed container output stream into stdout and stderr.
+// Each frame has an 8 byte header holding the stream type and the frame size.
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("docker logs failed: error reading stream header: %s", err.Error())
+		}
+
+		var out io.Writer
+		switch header[0] {
+		case 0, 1:
+			out = stdout
+		case 2:
+			out = stderr
+		default:
+			return fmt.Errorf("docker logs failed: unknown stream type %d", header[0])
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(out, r, size); err != nil {
+			return fmt.Errorf("docker logs failed: error reading stream: %s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//Image holds the parts of an image inspect response used by seed
+type Image struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Config      struct {
+		Labels     map[string]string `json:"Labels"`
+		Entrypoint []string          `json:"Entrypoint"`
+	} `json:"Config"`
+}
+
+//BuildOptions describes an image build
+type BuildOptions struct {
+	// ContextDir is the build context sent to the engine
+	ContextDir string
+
+	// Dockerfile to build; defaults to Dockerfile in ContextDir
+	Dockerfile string
+
+	Tag       string
+	CacheFrom []string
+	Labels    map[string]string
+
+	// Auth holds credentials for registries base images are pulled from, keyed by registry
+	Auth map[string]AuthConfig
+}
+
+//contextDockerfile is the name a Dockerfile outside the build context is given in the context archive
+const contextDockerfile = ".seed.Dockerfile"
+
+//InspectImage returns information about the named image
+func (c *Client) InspectImage(name string) (*Image, error) {
+	var image Image
+	err := c.doJSON("image inspect", "GET", "/images/"+name+"/json", nil, nil, &image)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+//ImageExists returns whether the named image exists locally
+func (c *Client) ImageExists(name string) (bool, error) {
+	_, err := c.InspectImage(name)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+//BuildImage builds an image, writing the build output to out
+func (c *Client) BuildImage(opts BuildOptions, out io.Writer) error {
+	dockerfile := opts.Dockerfile
+	if dockerfile == "" {
+		dockerfile = filepath.Join(opts.ContextDir, "Dockerfile")
+	}
+
+	// the engine can only read a Dockerfile that is part of the build context
+	contextName, err := filepath.Rel(opts.ContextDir, dockerfile)
+	extraDockerfile := ""
+	if err != nil || strings.HasPrefix(contextName, "..") {
+		contextName = contextDockerfile
+		extraDockerfile = dockerfile
+	}
+
+	query := url.Values{}
+	query.Set("t", opts.Tag)
+	query.Set("dockerfile", filepath.ToSlash(contextName))
+	query.Set("rm", "1")
+	if len(opts.CacheFrom) > 0 {
+		b, _ := json.Marshal(opts.CacheFrom)
+		query.Set("cachefrom", string(b))
+	}
+	if len(opts.Labels) > 0 {
+		b, _ := json.Marshal(opts.Labels)
+		query.Set("labels", string(b))
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-tar")
+	if len(opts.Auth) > 0 {
+		b, _ := json.Marshal(opts.Auth)
+		header.Set("X-Registry-Config", base64.URLEncoding.EncodeToString(b))
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeContext(writer, opts.ContextDir, extraDockerfile))
+	}()
+	defer reader.Close()
+
+	resp, err := c.do("build", "POST", "/build", query, reader, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readStream("build", resp.Body, out)
+}
+
+//PullImage pulls the named image, writing the pull progress to out
+func (c *Client) PullImage(name string, auth *AuthConfig, out io.Writer) error {
+	repo, tag := parseReference(name)
+	query := url.Values{}
+	query.Set("fromImage", repo)
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+
+	header := http.Header{}
+	header.Set("X-Registry-Auth", encodeAuth(auth))
+
+	resp, err := c.do("pull", "POST", "/images/create", query, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readStream("pull", resp.Body, out)
+}
+
+//TagImage tags the source image with the target name
+func (c *Client) TagImage(source, target string) error {
+	repo, tag := parseReference(target)
+	query := url.Values{}
+	query.Set("repo", repo)
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	return c.doJSON("tag", "POST", "/images/"+source+"/tag", query, nil, nil)
+}
+
+//PushImage pushes the named image to its registry, writing the push progress to out
+func (c *Client) PushImage(name string, auth *AuthConfig, out io.Writer) error {
+	repo, tag := parseReference(name)
+	query := url.Values{}
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+
+	header := http.Header{}
+	header.Set("X-Registry-Auth", encodeAuth(auth))
+
+	resp, err := c.do("push", "POST", "/images/"+repo+"/push", query, nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readStream("push", resp.Body, out)
+}
+
+//RemoveImage removes the named image
+func (c *Client) RemoveImage(name string, force bool) error {
+	query := url.Values{}
+	if force {
+		query.Set("force", "1")
+	}
+	return c.doJSON("image remove", "DELETE", "/images/"+name, query, nil, nil)
+}
+
+//parseReference splits an image name into its repository and tag. The tag is
+// empty if the name is a digest reference and defaults to latest otherwise.
+func parseReference(name string) (string, string) {
+	if strings.Contains(name, "@") {
+		return name, ""
+	}
+	i := strings.LastIndex(name, ":")
+	if i < 0 || strings.Contains(name[i+1:], "/") {
+		// no tag, or the colon belongs to a registry port
+		return name, "latest"
+	}
+	return name[:i], name[i+1:]
+}
+
+//writeContext writes the build context in dir as a tar archive, skipping any
+// paths excluded by its .dockerignore file. If dockerfile is not empty it is
+// added to the archive as contextDockerfile.
+func writeContext(w io.Writer, dir, dockerfile string) error {
+	excludes, err := readDockerignore(dir)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel != "Dockerfile" && rel != ".dockerignore" && isExcluded(rel, excludes) {
+			// excluded directories can only be skipped if nothing beneath them is re-included
+			if info.IsDir() && !hasExceptions(excludes) {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() {
+				return nil
+			}
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+
+	if dockerfile != "" {
+		info, err := os.Stat(dockerfile)
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = contextDockerfile
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err = copyFile(tw, dockerfile); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+//readDockerignore returns the patterns in the .dockerignore file in dir, if any
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+//isExcluded returns whether path matches the .dockerignore patterns. As with
+// docker, later patterns take precedence and patterns starting with ! re-include
+// matching paths. Patterns matching a directory exclude everything beneath it.
+func isExcluded(path string, patterns []string) bool {
+	excluded := false
+	for _, p := range patterns {
+		include := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		p = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "/")
+
+		matched, _ := filepath.Match(p, path)
+		if !matched {
+			// check if any parent directory of path matches
+			for dir := filepath.Dir(path); dir != "." && dir != "/" && !matched; dir = filepath.Dir(dir) {
+				matched, _ = filepath.Match(p, filepath.ToSlash(dir))
+			}
+		}
+		if matched {
+			excluded = !include
+		}
+	}
+	return excluded
+}
+
+//hasExceptions returns whether any of the .dockerignore patterns re-include paths
+func hasExceptions(patterns []string) bool {
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package dockerapi
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//RunConfig is a container described by docker run arguments
+type RunConfig struct {
+	Name       string
+	AutoRemove bool
+	Config     ContainerConfig
+}
+
+//ConfigFromRunArgs translates the docker run arguments built by seed into a container
+// config. args starts after the run command and supports the options seed uses:
+// --rm, --name, -v, -e, --env-file, -m, --shm-size, --cpus, --cpuset-cpus and --runtime.
+// The first argument that isn't an option is the image; the rest is the command.
+func ConfigFromRunArgs(args []string) (*RunConfig, error) {
+	run := &RunConfig{}
+	host := &run.Config.HostConfig
+
+	i := 0
+	for ; i < len(args) && strings.HasPrefix(args[i], "-"); i++ {
+		flag, value, hasValue := splitFlag(args[i])
+
+		switch flag {
+		case "--rm":
+			run.AutoRemove = true
+			continue
+		case "--name", "-v", "--volume", "-e", "--env", "--env-file", "-m", "--memory",
+			"--shm-size", "--cpus", "--cpuset-cpus", "--runtime":
+		default:
+			return nil, fmt.Errorf("unsupported docker run option %s", flag)
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("missing value for docker run option %s", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		var err error
+		switch flag {
+		case "--name":
+			run.Name = value
+		case "-v", "--volume":
+			host.Binds = append(host.Binds, value)
+		case "-e", "--env":
+			run.Config.Env = append(run.Config.Env, value)
+		case "--env-file":
+			var env []string
+			env, err = readEnvFile(value)
+			run.Config.Env = append(run.Config.Env, env...)
+		case "-m", "--memory":
+			host.Memory, err = parseBytes(value)
+		case "--shm-size":
+			host.ShmSize, err = parseBytes(value)
+		case "--cpus":
+			var cpus float64
+			cpus, err = strconv.ParseFloat(value, 64)
+			host.NanoCPUs = int64(cpus * 1e9)
+		case "--cpuset-cpus":
+			host.CpusetCpus = value
+		case "--runtime":
+			host.Runtime = value
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for docker run option %s: %s", flag, err.Error())
+		}
+	}
+
+	if i >= len(args) {
+		return nil, fmt.Errorf("no image given in docker run arguments")
+	}
+	run.Config.Image = args[i]
+	run.Config.Cmd = append(run.Config.Cmd, args[i+1:]...)
+
+	return run, nil
+}
+
+//splitFlag splits an option given as --flag=value
+func splitFlag(arg string) (string, string, bool) {
+	if i := strings.Index(arg, "="); i > 0 && strings.HasPrefix(arg, "--") {
+		return arg[:i], arg[i+1:], true
+	}
+	return arg, "", false
+}
+
+//parseBytes parses a docker memory size such as 512m or 2g into bytes
+func parseBytes(value string) (int64, error) {
+	units := map[byte]int64{'b': 1, 'k': 1 << 10, 'm': 1 << 20, 'g': 1 << 30}
+
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	if value != "" {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			multiplier = unit
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+//readEnvFile reads KEY=VALUE lines from a docker env file
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if !strings.Contains(line, "=") {
+			// docker passes a bare name through from the current environment
+			line = line + "=" + os.Getenv(line)
+		}
+		env = append(env, line)
+	}
+	return env, scanner.Err()
+}
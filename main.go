@@ -68,9 +68,9 @@ import (
 	"strconv"
 
 	"github.com/ngageoint/seed-cli/assets"
+	"github.com/ngageoint/seed-cli/cliutil"
 	"github.com/ngageoint/seed-cli/commands"
 	"github.com/ngageoint/seed-cli/constants"
-	"github.com/ngageoint/seed-common/objects"
 	"github.com/ngageoint/seed-common/util"
 	"github.com/zyxar/image2ascii/ascii"
 )
@@ -87,6 +87,7 @@ var searchCmd *flag.FlagSet
 var validateCmd *flag.FlagSet
 var versionCmd *flag.FlagSet
 var specCmd *flag.FlagSet
+var cacheCmd *flag.FlagSet
 var cliVersion string
 
 func main() {
@@ -165,6 +166,31 @@ func main() {
 		panic(util.Exit{0})
 	}
 
+	// seed cache: Lists or prunes the result cache. Does not require docker
+	if cacheCmd.Parsed() {
+		var err error
+		switch cacheCmd.Arg(0) {
+		case constants.CacheListCommand:
+			err = commands.CacheList()
+		case constants.CachePruneCommand:
+			// flags may also follow the subcommand
+			cacheCmd.Parse(cacheCmd.Args()[1:])
+			maxSize, _ := strconv.ParseFloat(cacheCmd.Lookup(constants.MaxSizeFlag).Value.String(), 64)
+			if cacheCmd.Lookup(constants.AllFlag).Value.String() == constants.TrueString {
+				maxSize = 0
+			}
+			err = commands.CachePrune(maxSize)
+		default:
+			cacheCmd.Usage()
+			panic(util.Exit{1})
+		}
+		if err != nil {
+			util.PrintUtil("%s\n", err.Error())
+			panic(util.Exit{1})
+		}
+		panic(util.Exit{0})
+	}
+
 	// Checks if we have elevated privileges to run docker. Only required for windows. Prints error message if privileges are missing.
 	if runtime.GOOS == "windows" {
 		util.CheckSudo()
@@ -222,16 +248,90 @@ func main() {
 
 	// seed batch: Run Docker image on all files in directory
 	if batchCmd.Parsed() {
+		// stop running containers and save the batch journal on Ctrl-C
+		cliutil.HandleInterrupts()
 		batchDir := batchCmd.Lookup(constants.JobDirectoryFlag).Value.String()
 		batchFile := batchCmd.Lookup(constants.BatchFlag).Value.String()
 		imageName := batchCmd.Lookup(constants.ImgNameFlag).Value.String()
 		manifest := batchCmd.Lookup(constants.ManifestFlag).Value.String()
-		settings := strings.Split(batchCmd.Lookup(constants.SettingFlag).Value.String(), ",")
-		mounts := strings.Split(batchCmd.Lookup(constants.MountFlag).Value.String(), ",")
+		settings := cliutil.FlagValues(batchCmd, constants.SettingFlag)
+		mounts := cliutil.FlagValues(batchCmd, constants.MountFlag)
 		outputDir := batchCmd.Lookup(constants.JobOutputDirFlag).Value.String()
 		rmFlag := batchCmd.Lookup(constants.RmFlag).Value.String() == constants.TrueString
 		metadataSchema := batchCmd.Lookup(constants.SchemaFlag).Value.String()
-		err := commands.BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchema, settings, mounts, rmFlag)
+		timeout, err := strconv.Atoi(batchCmd.Lookup(constants.TimeoutFlag).Value.String())
+		if err != nil {
+			util.PrintUtil("Error reading timeout flag: %s\n", err.Error())
+			panic(util.Exit{1})
+		}
+		resources := cliutil.FlagValues(batchCmd, constants.ResourcesFlag)
+		cpuset := batchCmd.Lookup(constants.CPUSetFlag).Value.String() == constants.TrueString
+		secretsFile := batchCmd.Lookup(constants.SecretsFlag).Value.String()
+		parallel, err := strconv.Atoi(batchCmd.Lookup(constants.ParallelFlag).Value.String())
+		if err != nil || parallel < 1 {
+			util.PrintUtil("ERROR: -%s must be a positive number of jobs\n", constants.ParallelFlag)
+			panic(util.Exit{1})
+		}
+		// every job would be pinned to the same cpus
+		if cpuset && parallel > 1 {
+			util.PrintUtil("ERROR: -%s can't be used with -%s greater than 1\n", constants.CPUSetFlag, constants.ParallelFlag)
+			panic(util.Exit{1})
+		}
+		resume := batchCmd.Lookup(constants.ResumeFlag).Value.String() == constants.TrueString
+		retryFailed, err := strconv.Atoi(batchCmd.Lookup(constants.RetryFailedFlag).Value.String())
+		if err != nil || retryFailed < 0 {
+			util.PrintUtil("ERROR: -%s must be zero or a positive number of retries\n", constants.RetryFailedFlag)
+			panic(util.Exit{1})
+		}
+		report := batchCmd.Lookup(constants.ReportFlag).Value.String()
+		if report != commands.ReportJSON && report != commands.ReportCSV && report != commands.ReportJUnit {
+			util.PrintUtil("ERROR: -%s must be one of %s, %s or %s\n", constants.ReportFlag,
+				commands.ReportJSON, commands.ReportCSV, commands.ReportJUnit)
+			panic(util.Exit{1})
+		}
+		mediaTypes := batchCmd.Lookup(constants.MediaTypesFlag).Value.String()
+		if !validMediaTypePolicy(mediaTypes) {
+			panic(util.Exit{1})
+		}
+		noCache := batchCmd.Lookup(constants.NoCacheFlag).Value.String() == constants.TrueString
+		logTimestamps := batchCmd.Lookup(constants.LogTimestampsFlag).Value.String() == constants.TrueString
+		opts := commands.RunOptions{Timeout: timeout, Resources: resources, CPUSet: cpuset, SecretsFile: secretsFile,
+			Parallel: parallel, Resume: resume, RetryFailed: retryFailed, Report: report, MediaTypes: mediaTypes,
+			NoCache: noCache, LogTimestamps: logTimestamps}
+
+		// values from a job spec file are used unless the flag is given
+		if specFile := batchCmd.Lookup(constants.JobSpecFlag).Value.String(); specFile != "" {
+			spec, err := commands.LoadJobSpec(specFile)
+			if err != nil {
+				util.PrintUtil("%s\n", err.Error())
+				panic(util.Exit{1})
+			}
+			given := givenFlags(batchCmd)
+			if spec.Image != "" && !given(constants.ImgNameFlag, constants.ShortImgNameFlag) {
+				imageName = spec.Image
+			}
+			if spec.Manifest != "" && !given(constants.ManifestFlag, constants.ShortManifestFlag) {
+				manifest = spec.Manifest
+			}
+			if spec.Batch != "" && !given(constants.BatchFlag, constants.ShortBatchFlag) {
+				batchFile = spec.Batch
+			}
+			if spec.Directory != "" && !given(constants.JobDirectoryFlag, constants.ShortJobDirectoryFlag) {
+				batchDir = spec.Directory
+			}
+			if spec.OutputDir != "" && !given(constants.JobOutputDirFlag, constants.ShortJobOutputDirFlag) {
+				outputDir = spec.OutputDir
+			}
+			if spec.Schema != "" && !given(constants.SchemaFlag, constants.ShortSchemaFlag) {
+				metadataSchema = spec.Schema
+			}
+			settings = commands.MergeArgs(spec.SettingArgs(), settings)
+			mounts = commands.MergeArgs(spec.MountArgs(), mounts)
+			opts.Resources = commands.MergeArgs(spec.ResourceArgs(), resources)
+			opts.JobSpec = &spec
+		}
+
+		err = commands.BatchRun(batchDir, batchFile, imageName, manifest, outputDir, metadataSchema, settings, mounts, rmFlag, opts)
 		if err != nil {
 			util.PrintUtil("%s\n", err.Error())
 			panic(util.Exit{1})
@@ -241,12 +341,14 @@ func main() {
 
 	// seed run: Runs docker image provided or found in seed manifest
 	if runCmd.Parsed() {
+		// stop the container and remove temp directories on Ctrl-C
+		cliutil.HandleInterrupts()
 		imageName := runCmd.Lookup(constants.ImgNameFlag).Value.String()
 		manifest := runCmd.Lookup(constants.ManifestFlag).Value.String()
-		inputs := strings.Split(runCmd.Lookup(constants.InputsFlag).Value.String(), ",")
-		json := strings.Split(runCmd.Lookup(constants.JsonFlag).Value.String(), ",")
-		settings := strings.Split(runCmd.Lookup(constants.SettingFlag).Value.String(), ",")
-		mounts := strings.Split(runCmd.Lookup(constants.MountFlag).Value.String(), ",")
+		inputs := cliutil.FlagValues(runCmd, constants.InputsFlag)
+		json := cliutil.FlagValues(runCmd, constants.JsonFlag)
+		settings := cliutil.FlagValues(runCmd, constants.SettingFlag)
+		mounts := cliutil.FlagValues(runCmd, constants.MountFlag)
 		outputDir := runCmd.Lookup(constants.JobOutputDirFlag).Value.String()
 		rmFlag := runCmd.Lookup(constants.RmFlag).Value.String() == constants.TrueString
 		quiet := runCmd.Lookup(constants.QuietFlag).Value.String() == constants.TrueString
@@ -259,6 +361,69 @@ func main() {
 			panic(util.Exit{1})
 		}
 
+		timeout, err := strconv.Atoi(runCmd.Lookup(constants.TimeoutFlag).Value.String())
+		if err != nil {
+			util.PrintUtil("Error reading timeout flag: %s\n", err.Error())
+			panic(util.Exit{1})
+		}
+		resources := cliutil.FlagValues(runCmd, constants.ResourcesFlag)
+		cpuset := runCmd.Lookup(constants.CPUSetFlag).Value.String() == constants.TrueString
+		secretsFile := runCmd.Lookup(constants.SecretsFlag).Value.String()
+		strictOutputs := runCmd.Lookup(constants.StrictOutputsFlag).Value.String() == constants.TrueString
+		mediaTypes := runCmd.Lookup(constants.MediaTypesFlag).Value.String()
+		if !validMediaTypePolicy(mediaTypes) {
+			panic(util.Exit{1})
+		}
+		dryRun := runCmd.Lookup(constants.DryRunFlag).Value.String() == constants.TrueString
+		outputFormat := runCmd.Lookup(constants.OutputFormatFlag).Value.String()
+		if outputFormat != constants.FormatText && outputFormat != constants.FormatJSON {
+			util.PrintUtil("ERROR: -%s must be %s or %s\n", constants.OutputFormatFlag, constants.FormatText, constants.FormatJSON)
+			panic(util.Exit{1})
+		}
+		debug := runCmd.Lookup(constants.DebugFlag).Value.String() == constants.TrueString
+		noCache := runCmd.Lookup(constants.NoCacheFlag).Value.String() == constants.TrueString
+		logTimestamps := runCmd.Lookup(constants.LogTimestampsFlag).Value.String() == constants.TrueString
+		inputFilter := commands.InputFilter{Include: patternList(runCmd.Lookup(constants.InputIncludeFlag).Value.String()),
+			Exclude: patternList(runCmd.Lookup(constants.InputExcludeFlag).Value.String())}
+		opts := commands.RunOptions{Timeout: timeout, Resources: resources, CPUSet: cpuset, SecretsFile: secretsFile,
+			StrictOutputs: strictOutputs, MediaTypes: mediaTypes, DryRun: dryRun, OutputFormat: outputFormat, Debug: debug,
+			NoCache: noCache, LogTimestamps: logTimestamps, InputFilter: inputFilter}
+
+		// values from a job spec file are used unless the flag is given
+		if specFile := runCmd.Lookup(constants.JobSpecFlag).Value.String(); specFile != "" {
+			spec, err := commands.LoadJobSpec(specFile)
+			if err != nil {
+				util.PrintUtil("%s\n", err.Error())
+				panic(util.Exit{1})
+			}
+			given := givenFlags(runCmd)
+			if spec.Image != "" && !given(constants.ImgNameFlag, constants.ShortImgNameFlag) {
+				imageName = spec.Image
+			}
+			if spec.Manifest != "" && !given(constants.ManifestFlag, constants.ShortManifestFlag) {
+				manifest = spec.Manifest
+			}
+			if spec.OutputDir != "" && !given(constants.JobOutputDirFlag, constants.ShortJobOutputDirFlag) {
+				outputDir = spec.OutputDir
+			}
+			if spec.Schema != "" && !given(constants.SchemaFlag, constants.ShortSchemaFlag) {
+				metadataSchema = spec.Schema
+			}
+			if spec.Repeat > 0 && !given(constants.RepeatFlag, constants.ShortRepeatFlag) {
+				reps = spec.Repeat
+			}
+			inputs = commands.MergeArgs(spec.InputArgs(), inputs)
+			json = commands.MergeArgs(spec.JsonArgs(), json)
+			settings = commands.MergeArgs(spec.SettingArgs(), settings)
+			mounts = commands.MergeArgs(spec.MountArgs(), mounts)
+			opts.Resources = commands.MergeArgs(spec.ResourceArgs(), resources)
+			opts.JobSpec = &spec
+		}
+
+		if quiet {
+			util.InitPrinter(util.Quiet, nil, nil)
+		}
+
 		// run for any additional repetitions
 		if reps > 1 {
 			for i := 0; i < reps; i++ {
@@ -266,18 +431,24 @@ func main() {
 				if outputDir != "" {
 					outputDirRep = outputDir + fmt.Sprintf("-%d", i)
 				}
-				_, err := commands.DockerRun(imageName, manifest, outputDirRep, metadataSchema, inputs, json, settings, mounts, rmFlag, quiet)
+				result, err := commands.DockerRun(imageName, manifest, outputDirRep, metadataSchema, inputs, json, settings, mounts, rmFlag, opts)
+				if !dryRun && !debug {
+					commands.PrintRunResult(result, outputFormat)
+				}
 				if err != nil {
 					util.PrintUtil("%s\n", err.Error())
-					panic(util.Exit{1})
+					panic(util.Exit{runExitCode(result)})
 				}
 			}
 		} else {
 			// run once
-			_, err = commands.DockerRun(imageName, manifest, outputDir, metadataSchema, inputs, json, settings, mounts, rmFlag, quiet)
+			result, err := commands.DockerRun(imageName, manifest, outputDir, metadataSchema, inputs, json, settings, mounts, rmFlag, opts)
+			if !dryRun && !debug {
+				commands.PrintRunResult(result, outputFormat)
+			}
 			if err != nil {
 				util.PrintUtil("%s\n", err.Error())
-				panic(util.Exit{1})
+				panic(util.Exit{runExitCode(result)})
 			}
 		}
 		panic(util.Exit{0})
@@ -470,13 +641,23 @@ func DefineBatchFlags() {
 	batchCmd.StringVar(&manifest, constants.ShortManifestFlag, ".",
 		"Manifest file to use (default is seed.manifest.json in the current directory).")
 
-	var settings objects.ArrayFlags
+	var jobSpec string
+	batchCmd.StringVar(&jobSpec, constants.JobSpecFlag, "",
+		"YAML or JSON file describing the job. Flags override values in the file")
+	batchCmd.StringVar(&jobSpec, constants.ShortJobSpecFlag, "",
+		"YAML or JSON file describing the job. Flags override values in the file")
+
+	var settings cliutil.KeyValueFlags
 	batchCmd.Var(&settings, constants.SettingFlag,
 		"Defines the value to be applied to setting")
 	batchCmd.Var(&settings, constants.ShortSettingFlag,
 		"Defines the value to be applied to setting")
 
-	var mounts objects.ArrayFlags
+	var secretsFile string
+	batchCmd.StringVar(&secretsFile, constants.SecretsFlag, "",
+		"File of SETTING_KEY=VALUE lines for secret settings (default reads secret settings from the environment)")
+
+	var mounts cliutil.KeyValueFlags
 	batchCmd.Var(&mounts, constants.MountFlag,
 		"Defines the full path to be mapped via mount")
 	batchCmd.Var(&mounts, constants.ShortMountFlag,
@@ -492,6 +673,48 @@ func DefineBatchFlags() {
 	batchCmd.BoolVar(&rmVar, constants.RmFlag, false,
 		"Specifying the -rm flag automatically removes the image after executing docker run")
 
+	var timeout int
+	batchCmd.IntVar(&timeout, constants.TimeoutFlag, 0,
+		"Job timeout in seconds for each run (default is job.timeout from the seed manifest)")
+	batchCmd.IntVar(&timeout, constants.ShortTimeoutFlag, 0,
+		"Job timeout in seconds for each run (default is job.timeout from the seed manifest)")
+
+	var resources cliutil.KeyValueFlags
+	batchCmd.Var(&resources, constants.ResourcesFlag,
+		"Overrides a scalar resource in the format NAME=VALUE, NAME=min:VALUE, NAME=max:VALUE or NAME=xFACTOR")
+
+	var cpuset bool
+	batchCmd.BoolVar(&cpuset, constants.CPUSetFlag, false,
+		"Pins the job to its cpus resource with --cpuset-cpus instead of limiting with --cpus")
+
+	var parallel int
+	batchCmd.IntVar(&parallel, constants.ParallelFlag, 1,
+		"Number of jobs to run at once")
+
+	var resume bool
+	batchCmd.BoolVar(&resume, constants.ResumeFlag, false,
+		"Resumes the batch in the output directory, skipping jobs that already succeeded")
+
+	var retryFailed int
+	batchCmd.IntVar(&retryFailed, constants.RetryFailedFlag, 0,
+		"Number of times to retry failed jobs")
+
+	var report string
+	batchCmd.StringVar(&report, constants.ReportFlag, commands.ReportJSON,
+		"Format of the batch report: json, csv or junit")
+
+	var mediaTypes string
+	batchCmd.StringVar(&mediaTypes, constants.MediaTypesFlag, constants.MediaTypeWarn,
+		"Whether to warn, fail or skip the check when an input file's media type isn't in the seed manifest")
+
+	var noCache bool
+	batchCmd.BoolVar(&noCache, constants.NoCacheFlag, false,
+		"Runs the jobs without restoring or storing their output in the result cache")
+
+	var logTimestamps bool
+	batchCmd.BoolVar(&logTimestamps, constants.LogTimestampsFlag, false,
+		"Prefixes each line of the jobs' stdout.log and stderr.log with the time it was written")
+
 	var metadataSchema string
 	batchCmd.StringVar(&metadataSchema, constants.SchemaFlag, "",
 		"Metadata schema file to override built in schema in validating side-car metadata files")
@@ -521,25 +744,35 @@ func DefineRunFlags() {
 	runCmd.StringVar(&manifest, constants.ShortManifestFlag, ".",
 		"Manifest file to use (default is seed.manifest.json in the current directory).")
 
-	var inputs objects.ArrayFlags
+	var jobSpec string
+	runCmd.StringVar(&jobSpec, constants.JobSpecFlag, "",
+		"YAML or JSON file describing the job. Flags override values in the file")
+	runCmd.StringVar(&jobSpec, constants.ShortJobSpecFlag, "",
+		"YAML or JSON file describing the job. Flags override values in the file")
+
+	var inputs cliutil.KeyValueFlags
 	runCmd.Var(&inputs, constants.InputsFlag,
 		"Defines the full path to any input data arguments")
 	runCmd.Var(&inputs, constants.ShortInputsFlag,
 		"Defines the full path to input data arguments")
 
-	var json objects.ArrayFlags
+	var json cliutil.KeyValueFlags
 	runCmd.Var(&json, constants.JsonFlag,
 		"Defines input json arguments")
 	runCmd.Var(&json, constants.ShortJsonFlag,
 		"Defines input json arguments")
 
-	var settings objects.ArrayFlags
+	var settings cliutil.KeyValueFlags
 	runCmd.Var(&settings, constants.SettingFlag,
 		"Defines the value to be applied to setting")
 	runCmd.Var(&settings, constants.ShortSettingFlag,
 		"Defines the value to be applied to setting")
 
-	var mounts objects.ArrayFlags
+	var secretsFile string
+	runCmd.StringVar(&secretsFile, constants.SecretsFlag, "",
+		"File of SETTING_KEY=VALUE lines for secret settings (default reads secret settings from the environment)")
+
+	var mounts cliutil.KeyValueFlags
 	runCmd.Var(&mounts, constants.MountFlag,
 		"Defines the full path to be mapped via mount")
 	runCmd.Var(&mounts, constants.ShortMountFlag,
@@ -573,6 +806,56 @@ func DefineRunFlags() {
 	runCmd.IntVar(&repeat, constants.ShortRepeatFlag, 1,
 		"Run the docker image the specified number of times")
 
+	var timeout int
+	runCmd.IntVar(&timeout, constants.TimeoutFlag, 0,
+		"Job timeout in seconds (default is job.timeout from the seed manifest)")
+	runCmd.IntVar(&timeout, constants.ShortTimeoutFlag, 0,
+		"Job timeout in seconds (default is job.timeout from the seed manifest)")
+
+	var resources cliutil.KeyValueFlags
+	runCmd.Var(&resources, constants.ResourcesFlag,
+		"Overrides a scalar resource in the format NAME=VALUE, NAME=min:VALUE, NAME=max:VALUE or NAME=xFACTOR")
+
+	var cpuset bool
+	runCmd.BoolVar(&cpuset, constants.CPUSetFlag, false,
+		"Pins the job to its cpus resource with --cpuset-cpus instead of limiting with --cpus")
+
+	var strictOutputs bool
+	runCmd.BoolVar(&strictOutputs, constants.StrictOutputsFlag, false,
+		"Exits with a non-zero status if the job's output fails validation")
+
+	var mediaTypes string
+	runCmd.StringVar(&mediaTypes, constants.MediaTypesFlag, constants.MediaTypeWarn,
+		"Whether to warn, fail or skip the check when an input file's media type isn't in the seed manifest")
+
+	var inputInclude string
+	runCmd.StringVar(&inputInclude, constants.InputIncludeFlag, "",
+		"Comma separated patterns of the files to stage from directories given for multiple file inputs")
+
+	var inputExclude string
+	runCmd.StringVar(&inputExclude, constants.InputExcludeFlag, "",
+		"Comma separated patterns of the files and subdirectories to leave out of directories given for multiple file inputs")
+
+	var dryRun bool
+	runCmd.BoolVar(&dryRun, constants.DryRunFlag, false,
+		"Prints the docker run command, expanded job command and resource allocations without running the job")
+
+	var outputFormat string
+	runCmd.StringVar(&outputFormat, constants.OutputFormatFlag, constants.FormatText,
+		"Format of the run result or dry run output: text or json")
+
+	var debug bool
+	runCmd.BoolVar(&debug, constants.DebugFlag, false,
+		"Starts an interactive shell in the prepared job container with a script that runs the job")
+
+	var noCache bool
+	runCmd.BoolVar(&noCache, constants.NoCacheFlag, false,
+		"Runs the job without restoring or storing its output in the result cache")
+
+	var logTimestamps bool
+	runCmd.BoolVar(&logTimestamps, constants.LogTimestampsFlag, false,
+		"Prefixes each line of the job's stdout.log and stderr.log with the time it was written")
+
 	// Run usage function
 	runCmd.Usage = func() {
 		PrintASCIIArt()
@@ -785,6 +1068,22 @@ func DefineValidateFlags() {
 	}
 }
 
+//DefineCacheFlags defines the flags for the seed cache command
+func DefineCacheFlags() {
+	cacheCmd = flag.NewFlagSet(constants.CacheCommand, flag.ExitOnError)
+	var maxSize float64
+	cacheCmd.Float64Var(&maxSize, constants.MaxSizeFlag, -1,
+		"Size in MiB to prune the result cache to (default is the cache size limit)")
+	var all bool
+	cacheCmd.BoolVar(&all, constants.AllFlag, false,
+		"Removes every entry from the result cache")
+
+	cacheCmd.Usage = func() {
+		PrintASCIIArt()
+		commands.PrintCacheUsage()
+	}
+}
+
 //DefineFlags defines the flags available for the seed runner.
 func DefineFlags() {
 	// Seed subcommand flags
@@ -798,6 +1097,7 @@ func DefineFlags() {
 	DefineUnpublishFlags()
 	DefinePullFlags()
 	DefineValidateFlags()
+	DefineCacheFlags()
 	versionCmd = flag.NewFlagSet(constants.VersionCommand, flag.ExitOnError)
 	versionCmd.Usage = func() {
 		PrintVersionUsage()
@@ -808,6 +1108,14 @@ func DefineFlags() {
 		PrintSpecUsage()
 	}
 
+	// Select the container runtime before parsing the command
+	args, err := parseRuntimeFlag(os.Args)
+	if err != nil {
+		util.PrintUtil("%s\n", err.Error())
+		panic(util.Exit{1})
+	}
+	os.Args = args
+
 	// Print usage if no command given
 	if len(os.Args) == 1 {
 		PrintUsage()
@@ -867,6 +1175,10 @@ func DefineFlags() {
 		cmd = validateCmd
 		minArgs = 2
 
+	case constants.CacheCommand:
+		cmd = cacheCmd
+		minArgs = 3
+
 	case constants.VersionCommand:
 		versionCmd.Parse(os.Args[2:])
 		PrintVersion()
@@ -893,14 +1205,78 @@ func DefineFlags() {
 	}
 }
 
+//parseRuntimeFlag selects the container runtime named by the global runtime flag, which
+// must be given before the command, or by the SEED_RUNTIME environment variable.
+// Returns args with the runtime flag removed.
+func parseRuntimeFlag(args []string) ([]string, error) {
+	name := os.Getenv(constants.RuntimeEnv)
+	if len(args) > 1 {
+		// accept both -runtime and --runtime
+		arg := args[1]
+		if strings.HasPrefix(arg, "--") {
+			arg = arg[1:]
+		}
+		flagName := "-" + constants.RuntimeFlag
+		switch {
+		case arg == flagName:
+			if len(args) < 3 {
+				return args, fmt.Errorf("ERROR: %s requires a value", flagName)
+			}
+			name = args[2]
+			args = append([]string{args[0]}, args[3:]...)
+		case strings.HasPrefix(arg, flagName+"="):
+			name = strings.TrimPrefix(arg, flagName+"=")
+			args = append([]string{args[0]}, args[2:]...)
+		}
+	}
+
+	if name == "" {
+		return args, nil
+	}
+	return args, cliutil.SetRuntime(name)
+}
+
+//validMediaTypePolicy returns whether policy is a valid -input-media-types value, printing an error if not
+func validMediaTypePolicy(policy string) bool {
+	switch policy {
+	case constants.MediaTypeWarn, constants.MediaTypeFail, constants.MediaTypeOff:
+		return true
+	}
+	util.PrintUtil("ERROR: -%s must be one of %s, %s or %s\n", constants.MediaTypesFlag,
+		constants.MediaTypeWarn, constants.MediaTypeFail, constants.MediaTypeOff)
+	return false
+}
+
+//patternList returns the patterns in a comma separated flag value
+func patternList(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+//runExitCode returns the exit code seed should exit with after a failed run.
+// Timed out jobs exit with constants.TimeoutExitCode so they can be told apart
+// from other failures.
+func runExitCode(result commands.RunResult) int {
+	if result.TimedOut {
+		return constants.TimeoutExitCode
+	}
+	return 1
+}
+
 //PrintUsage prints the seed usage arguments
 func PrintUsage() {
 	PrintASCIIArt()
-	util.PrintUtil("\nUsage:\tseed COMMAND\n\n")
+	util.PrintUtil("\nUsage:\tseed [-%s RUNTIME] COMMAND\n\n", constants.RuntimeFlag)
 	util.PrintUtil("A tool for assisting in creating seed spec compliant algorithms\n\n")
 	util.PrintUtil("Commands:\n")
 	util.PrintUtil("  build \tBuilds Seed compliant Docker image\n")
 	util.PrintUtil("  batch \tExecutes Seed compliant docker image over multiple iterations\n")
+	util.PrintUtil("  cache \tLists or prunes the cached outputs of earlier runs\n")
 	util.PrintUtil("  init  \tInitialize new project with example seed.manifest.json file\n")
 	util.PrintUtil("  list  \tLists all Seed compliant images residing on the local system\n")
 	util.PrintUtil("  publish\tPublishes Seed compliant images to remote Docker registry\n")
@@ -911,6 +1287,9 @@ func PrintUsage() {
 	util.PrintUtil("  unpublish\tRemoves images from remote Docker registry\n")
 	util.PrintUtil("  validate\tValidates a Seed spec\n")
 	util.PrintUtil("  version\tPrints the version of Seed spec\n")
+	util.PrintUtil("\nGlobal Options:\n")
+	util.PrintUtil("  -%s\tContainer runtime to use: %s or %s (default is $%s, then %s)\n",
+		constants.RuntimeFlag, constants.DockerRuntime, constants.PodmanRuntime, constants.RuntimeEnv, constants.DockerRuntime)
 	util.PrintUtil("\nRun 'seed COMMAND --help' for more information on a command.\n")
 	panic(util.Exit{0})
 }
@@ -970,3 +1349,20 @@ func PrintASCIIArt() {
 	a, _ := ascii.Decode(bytes.NewReader(imgBytes), ascii.Options{Color: true, Height: 12})
 	a.WriteTo(os.Stdout)
 }
+
+//givenFlags returns a function reporting whether any of the named flags was given on
+// the command line, as opposed to having its default value
+func givenFlags(cmd *flag.FlagSet) func(names ...string) bool {
+	set := map[string]bool{}
+	cmd.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return func(names ...string) bool {
+		for _, name := range names {
+			if set[name] {
+				return true
+			}
+		}
+		return false
+	}
+}
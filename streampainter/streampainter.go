@@ -1,24 +1,157 @@
 package streampainter
 
 import (
+	"bytes"
+	"io"
 	"os"
+	"regexp"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 )
 
+//MaxLineLength is the longest partial line a StreamPainter holds before writing it
+// without waiting for the end of the line
+const MaxLineLength = 64 * 1024
+
+var errorLevel = regexp.MustCompile(`\b(ERROR|FATAL|CRITICAL|PANIC)\b`)
+var warnLevel = regexp.MustCompile(`\bWARN(ING)?\b`)
+
+//StreamPainter is an io.Writer that colours each line written through it and writes
+// it to another writer. Lines mentioning ERROR are painted bold red and lines mentioning
+// WARN yellow; other lines are painted in the painter's colour. Partial lines are held
+// until they're complete or Flush is called, so lines are never split between writes.
 type StreamPainter struct {
 	paintColor color.Attribute
+	out        io.Writer
+	prefix     string
+	colorize   bool
+
+	mu  sync.Mutex
+	buf []byte
+
+	// midLine is set when part of a line has been written without its end
+	midLine bool
 }
 
 /*
- * NewStreamPainter returns new streampainter
+ * NewStreamPainter returns new streampainter writing to out. Colour is enabled when out
+ * is a terminal and the NO_COLOR environment variable isn't set.
  */
-func NewStreamPainter(textColor color.Attribute) *StreamPainter {
-	return &StreamPainter{textColor}
+func NewStreamPainter(out io.Writer, textColor color.Attribute) *StreamPainter {
+	return &StreamPainter{paintColor: textColor, out: out, colorize: ColorEnabled(out)}
+}
+
+//ColorEnabled returns whether output written to out should be coloured: out is a
+// terminal, NO_COLOR isn't set and TERM isn't dumb
+func ColorEnabled(out io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := out.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
+
+//SetPrefix sets the text written before each line, i.e. "[job-3] "
+func (w *StreamPainter) SetPrefix(prefix string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.prefix = prefix
+}
+
+//SetColor enables or disables colouring regardless of where the painter writes to
+func (w *StreamPainter) SetColor(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.colorize = enabled
 }
 
 func (w *StreamPainter) Write(p []byte) (int, error) {
-	n := len(p)
-	color.New(w.paintColor).Fprintf(os.Stderr, string(p[:n]))
-	return n, nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	start := 0
+	var err error
+	for err == nil {
+		i := bytes.IndexByte(w.buf[start:], '\n')
+		if i < 0 {
+			break
+		}
+		err = w.writeLine(w.buf[start:start+i], true)
+		start += i + 1
+	}
+
+	// write very long lines in parts rather than buffering them in full, without
+	// splitting a multibyte character
+	if err == nil && len(w.buf)-start >= MaxLineLength {
+		cut := start + runeBoundary(w.buf[start:])
+		err = w.writeLine(w.buf[start:cut], false)
+		start = cut
+	}
+
+	// keep the partial line at the start of the buffer
+	w.buf = append(w.buf[:0], w.buf[start:]...)
+	return len(p), err
+}
+
+//Flush writes any partial line held by the painter
+func (w *StreamPainter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.writeLine(w.buf, false)
+	w.buf = nil
+	return err
+}
+
+//writeLine writes the prefix and the painted line in a single write, so lines from
+// painters sharing a writer don't interleave
+func (w *StreamPainter) writeLine(line []byte, newline bool) error {
+	var out bytes.Buffer
+	if !w.midLine {
+		out.WriteString(w.prefix)
+	}
+	if w.colorize {
+		c := color.New(lineColor(line, w.paintColor)...)
+		c.EnableColor()
+		out.WriteString(c.Sprint(string(line)))
+	} else {
+		out.Write(line)
+	}
+	if newline {
+		out.WriteByte('\n')
+	}
+	w.midLine = !newline
+	_, err := w.out.Write(out.Bytes())
+	return err
+}
+
+//lineColor returns the colour attributes of a line given its level
+func lineColor(line []byte, defaultColor color.Attribute) []color.Attribute {
+	switch {
+	case errorLevel.Match(line):
+		return []color.Attribute{color.FgRed, color.Bold}
+	case warnLevel.Match(line):
+		return []color.Attribute{color.FgYellow}
+	}
+	return []color.Attribute{defaultColor}
+}
+
+//runeBoundary returns the length of the longest prefix of p that doesn't end partway
+// through a multibyte character
+func runeBoundary(p []byte) int {
+	for i := len(p); i > 0 && i > len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i-1]) {
+			if utf8.FullRune(p[i-1:]) {
+				return len(p)
+			}
+			return i - 1
+		}
+	}
+	return len(p)
 }
@@ -1,18 +1,172 @@
 package streampainter
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/fatih/color"
 )
 
 func TestStreamPainter(t *testing.T) {
-	writer := NewStreamPainter(color.FgRed)
-	_, err := writer.Write([]byte("should be in red"))
+	var out bytes.Buffer
+	writer := NewStreamPainter(&out, color.FgRed)
+	_, err := writer.Write([]byte("should be in red\n"))
 	if err != nil {
 		t.Errorf("Error should be 'nil', but was %v", err.Error())
 	}
 	if writer.paintColor != color.FgRed {
 		t.Errorf("Assigned color expected FgRed, but was %v", writer.paintColor)
 	}
+	if out.String() != "should be in red\n" {
+		t.Errorf("Output to a buffer expected to be uncoloured, but was %q", out.String())
+	}
+}
+
+func TestStreamPainterLines(t *testing.T) {
+	cases := []struct {
+		name     string
+		writes   []string
+		prefix   string
+		expected []string
+	}{
+		{"percent signs", []string{"100% done %s %d\n"}, "", []string{"100% done %s %d\n"}},
+		{"partial lines", []string{"par", "tial\nnext"}, "", []string{"partial\n"}},
+		{"several lines", []string{"one\ntwo\n"}, "", []string{"one\n", "two\n"}},
+		{"split multibyte character", []string{"caf\xc3", "\xa9\n"}, "", []string{"café\n"}},
+		{"prefix", []string{"one\ntw", "o\n"}, "[job-3] ", []string{"[job-3] one\n", "[job-3] two\n"}},
+		{"empty line", []string{"\n"}, "[job-3] ", []string{"[job-3] \n"}},
+	}
+
+	for _, c := range cases {
+		var out recorder
+		writer := NewStreamPainter(&out, color.FgRed)
+		writer.SetPrefix(c.prefix)
+		for _, s := range c.writes {
+			if n, err := writer.Write([]byte(s)); n != len(s) || err != nil {
+				t.Errorf("%s: Write(%q) == %v, %v, expected %v, nil", c.name, s, n, err, len(s))
+			}
+		}
+		if strings.Join(out.writes, "|") != strings.Join(c.expected, "|") {
+			t.Errorf("%s: writes == %q, expected %q", c.name, out.writes, c.expected)
+		}
+	}
+}
+
+func TestStreamPainterFlush(t *testing.T) {
+	var out recorder
+	writer := NewStreamPainter(&out, color.FgRed)
+	writer.SetPrefix("> ")
+	writer.Write([]byte("one\nno newline"))
+	if err := writer.Flush(); err != nil {
+		t.Errorf("Flush returned an error: %v", err)
+	}
+	writer.Flush()
+	if expected := []string{"> one\n", "> no newline"}; strings.Join(out.writes, "|") != strings.Join(expected, "|") {
+		t.Errorf("writes after Flush == %q, expected %q", out.writes, expected)
+	}
+}
+
+func TestStreamPainterLongLine(t *testing.T) {
+	var out recorder
+	writer := NewStreamPainter(&out, color.FgRed)
+	writer.SetPrefix("> ")
+
+	// the line is written once it's too long to hold, without splitting the final é
+	line := strings.Repeat("a", MaxLineLength-1) + "é"
+	writer.Write([]byte(line[:len(line)-1]))
+	if len(out.writes) != 1 || out.writes[0] != "> "+line[:len(line)-2] {
+		t.Fatalf("writes of a long line == %v writes, expected the line without its partial character", len(out.writes))
+	}
+	writer.Write([]byte(line[len(line)-1:] + " end\n"))
+	if len(out.writes) != 2 || out.writes[1] != "é end\n" {
+		t.Errorf("rest of a long line == %q, expected %q without a prefix", out.writes[1:], "é end\n")
+	}
+}
+
+func TestStreamPainterColor(t *testing.T) {
+	red := color.New(color.FgRed)
+	red.EnableColor()
+	boldRed := color.New(color.FgRed, color.Bold)
+	boldRed.EnableColor()
+	yellow := color.New(color.FgYellow)
+	yellow.EnableColor()
+
+	cases := []struct {
+		line     string
+		expected string
+	}{
+		{"processing", red.Sprint("processing")},
+		{"ERROR: no input", boldRed.Sprint("ERROR: no input")},
+		{"2019-01-01 FATAL out of memory", boldRed.Sprint("2019-01-01 FATAL out of memory")},
+		{"WARNING: slow", yellow.Sprint("WARNING: slow")},
+		{"[WARN] retrying", yellow.Sprint("[WARN] retrying")},
+		{"no ERRORS here", red.Sprint("no ERRORS here")},
+		{"50% %d", red.Sprint("50", "% %d")},
+	}
+
+	for _, c := range cases {
+		var out bytes.Buffer
+		writer := NewStreamPainter(&out, color.FgRed)
+		writer.SetColor(true)
+		writer.SetPrefix("[job-1] ")
+		writer.Write([]byte(c.line + "\n"))
+		if expected := "[job-1] " + c.expected + "\n"; out.String() != expected {
+			t.Errorf("painted %q == %q, expected %q", c.line, out.String(), expected)
+		}
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	noColor, set := os.LookupEnv("NO_COLOR")
+	defer func() {
+		if set {
+			os.Setenv("NO_COLOR", noColor)
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+	}()
+
+	os.Unsetenv("NO_COLOR")
+	if ColorEnabled(&bytes.Buffer{}) {
+		t.Errorf("ColorEnabled for a buffer == true, expected false")
+	}
+	file, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if ColorEnabled(file) {
+		t.Errorf("ColorEnabled for %s == true, expected false", os.DevNull)
+	}
+
+	os.Setenv("NO_COLOR", "1")
+	if ColorEnabled(os.Stderr) {
+		t.Errorf("ColorEnabled with NO_COLOR set == true, expected false")
+	}
+}
+
+func TestStreamPainterError(t *testing.T) {
+	writer := NewStreamPainter(failWriter{}, color.FgRed)
+	if _, err := writer.Write([]byte("line\n")); err == nil {
+		t.Errorf("Write to a failing writer returned no error")
+	}
+}
+
+//recorder records each write made to it
+type recorder struct {
+	writes []string
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, string(p))
+	return len(p), nil
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
 }